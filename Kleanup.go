@@ -5,11 +5,12 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"os"
+	"maps"
 	"reflect"
+	"slices"
 	"strings"
 
-	"gopkg.in/yaml.v2"
+	yamlv3 "go.yaml.in/yaml/v3"
 )
 
 // KubernetesObject represents the basic structure of Kubernetes objects.
@@ -22,7 +23,17 @@ type KubernetesObject struct {
 	Data       map[string]interface{} `yaml:"data,omitempty"`       // For ConfigMaps/Secrets
 	StringData map[string]interface{} `yaml:"stringData,omitempty"` // For Secrets
 	Type       string                 `yaml:"type,omitempty"`       // e.g., for Secrets
-	// Add other common top-level fields if needed
+	Items      []KubernetesObject     `yaml:"items,omitempty"`      // For List kinds (List, DeploymentList, ...)
+	// Extra holds every other top-level field (ClusterRole rules, RoleBinding roleRef/subjects,
+	// ConfigMap binaryData, CRD fields, ...) so that unknown data survives the round-trip untouched.
+	Extra map[string]interface{} `yaml:",inline"`
+
+	// Changes records the fields removed while cleaning, see recordChanges. It is never encoded.
+	Changes *ChangeRecorder `yaml:"-" json:"-"`
+
+	node      *yamlv3.Node // The node the object was decoded from, used to preserve its formatting
+	namespace string       // metadata.namespace before cleaning (or its retargeted namespace), used to name split output files
+	dropped   string       // Set by cleaning, to the reason, when the object must not be written (e.g. a duplicate)
 }
 
 // CleanupOptions defines options to customize the cleanup process.
@@ -35,37 +46,32 @@ type CleanupOptions struct {
 	RemoveAnnotations     []string // annotations to remove
 	RemoveEmpty           bool     // Remove empty fields after cleaning
 	CleanupFinalizers     bool     // Remove finalizers
-	RevertToDeployment    bool     // Attempt to reconstruct Deployment from Pod
+	RevertToDeployment    bool     // Attempt to reconstruct the controller (Deployment, StatefulSet, ...) of Pods
 	PreserveResourceState bool     // Keep resource state related fields
 	ResourceStateMode     string   // "Desired" or "Runtime" cleanup mode
-}
-
-// resourceStateFields tracks which fields represent desired vs runtime state using dot notation
-var resourceStateFields = map[string]map[string]bool{
-	"Deployment": {
-		"metadata.generation": false, // runtime state
-		"spec.replicas":       true,  // desired state
-		"spec.strategy":       true,  // desired state
-		"spec.template":       true,  // desired state
-		"status":              false, // runtime state
-	},
-	"Service": {
-		"spec.clusterIP":  false, // runtime state
-		"spec.clusterIPs": false, // runtime state - Added
-		"spec.ports":      true,  // desired state
-		"spec.selector":   true,  // desired state
-		"status":          false, // runtime state - Added
-	},
-	"Pod": {
-		"metadata.generation": false, // runtime state - Added
-		"spec.containers":     true,  // desired state
-		"spec.initContainers": true,  // desired state - Added
-		"spec.nodeName":       false, // runtime state
-		"spec.nodeSelector":   true,  // desired state
-		"spec.volumes":        true,  // desired state
-		"status":              false, // runtime state
-	},
-	// Add more kinds and their fields as needed
+	ExplodeLists          bool     // Emit List items as separate documents instead of a cleaned List
+	KeepDefaults          bool     // Keep fields that still hold their API server default value
+	KeyOrder              string   // Key order of the output: "canonical" or "preserve" (input order)
+	OutputFormat          string   // "yaml", "json" or "jsonl"
+	CollapseOwned         bool     // Drop objects and fields managed by a controller that is part of the input (ReplicaSets, Pods, autoscaled replicas, ...)
+	RemoveSuspend         bool     // Remove spec.suspend from Jobs and CronJobs, so they run once applied
+	VolumeBinding         string   // "unbind" (claims are provisioned again) or "preserve" (keep claims bound to their volumes)
+	SkipSystemRBAC        bool     // Drop system:* Roles, ClusterRoles and bindings, which every cluster manages itself
+
+	NamespaceMap map[string]string // Namespaces to rewrite (source to target) instead of removing, with every reference to them
+
+	ExtraAnnotationPrefixes []string                   // Annotation prefixes to remove in addition to the built-in list
+	ExtraPodFields          []string                   // Pod spec fields to remove in addition to the built-in list
+	ExtraContainerFields    []string                   // Container fields to remove in addition to the built-in list
+	KindOverrides           map[string]*ProfileOptions // Per-kind option overrides, usually loaded from a profile
+	FieldRules              []FieldRule                // User-defined rules applied after the built-in cleaners
+	Schemas                 *SchemaRegistry            // Schemas classifying desired/runtime fields; nil uses the bundled snapshot
+	Report                  *CleanupReport             // When set, collects the fields removed from every object
+	Split                   *SplitWriter               // When set, writes every object to its own file instead of the output
+	Chart                   *ChartWriter               // When set, collects every object into a Helm chart instead of the output
+	KeepUnknown             bool                       // Write documents that are not Kubernetes objects unchanged instead of skipping them (set when rewriting files)
+	Owners                  *ownerIndex                // The objects of every input file, to collapse children into owners in other files; nil indexes each manifest
+	Reconstructed           map[string]bool            // Controllers reconstructed from Pods in any input file, see podController.key; nil tracks each manifest
 }
 
 // MetadataCleaner defines an interface for cleaning object metadata.
@@ -87,74 +93,67 @@ func (c *GenericMetadataCleaner) Clean(obj *KubernetesObject, options *CleanupOp
 	}
 	metadata := obj.Metadata
 
-	// Determine fields to remove based on options and state preservation
-	fieldsToRemove := map[string]bool{
-		"creationTimestamp": true,
-		"resourceVersion":   true,
-		"selfLink":          true,
-		"uid":               true,
-		"ownerReferences":   true,
+	// Determine fields to remove based on options and state preservation, with the recorded reason
+	fieldsToRemove := map[string]string{
+		"creationTimestamp": "GenericMetadataCleaner:runtime",
+		"resourceVersion":   "GenericMetadataCleaner:runtime",
+		"selfLink":          "GenericMetadataCleaner:runtime",
+		"uid":               "GenericMetadataCleaner:runtime",
+		"ownerReferences":   "GenericMetadataCleaner:runtime",
 	}
 
 	// Handle generation based on state preservation first
 	isGenerationRuntime := false
-	if stateFields, ok := resourceStateFields[obj.Kind]; ok {
-		if isDesired, exists := stateFields["metadata.generation"]; exists && !isDesired {
-			isGenerationRuntime = true
-		}
+	if isDesired, exists := schemasFor(options).stateFields(obj)["metadata.generation"]; exists && !isDesired {
+		isGenerationRuntime = true
 	}
 	if !(options.PreserveResourceState && options.ResourceStateMode == "Runtime" && isGenerationRuntime) {
-		fieldsToRemove["generation"] = true // Remove generation unless preserving runtime state
+		fieldsToRemove["generation"] = "GenericMetadataCleaner:runtime" // Remove generation unless preserving runtime state
 	}
 
 	if options.RemoveManagedFields {
-		fieldsToRemove["managedFields"] = true
+		fieldsToRemove["managedFields"] = "GenericMetadataCleaner:option"
 	}
 	if options.CleanupFinalizers {
-		fieldsToRemove["finalizers"] = true
+		fieldsToRemove["finalizers"] = "GenericMetadataCleaner:option"
 	}
-	if options.RemoveNamespace {
-		fieldsToRemove["namespace"] = true
+	if _, retargeted := options.NamespaceMap[obj.namespace]; options.RemoveNamespace && !retargeted {
+		fieldsToRemove["namespace"] = "GenericMetadataCleaner:option" // Mapped namespaces are rewritten instead
 	}
 
-	for field := range fieldsToRemove {
-		delete(metadata, field)
+	for _, field := range slices.Sorted(maps.Keys(fieldsToRemove)) {
+		obj.Changes.remove(metadata, field, fieldsToRemove[field])
 	}
 
 	// Clean annotations
 	if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
-		cleanAnnotations(annotations, options.RemoveAnnotations)
+		cleanAnnotations(annotations, options.RemoveAnnotations, options.ExtraAnnotationPrefixes, obj.Changes)
 		if len(annotations) == 0 {
-			delete(metadata, "annotations") // Remove empty annotations map
+			obj.Changes.remove(metadata, "annotations", "GenericMetadataCleaner:empty") // Remove empty annotations map
 		}
 	}
 	// Clean labels
 	if labels, ok := metadata["labels"].(map[string]interface{}); ok {
-		cleanLabels(labels, options.RemoveLabels)
+		cleanLabels(labels, options.RemoveLabels, obj.Changes)
 		if len(labels) == 0 {
-			delete(metadata, "labels") // Remove empty labels map
+			obj.Changes.remove(metadata, "labels", "GenericMetadataCleaner:empty") // Remove empty labels map
 		}
 	}
 
 	// Note: Removal of the entire metadata map if empty happens in removeEmptyFields
 }
 
-func cleanLabels(labels map[string]interface{}, removeLabels []string) {
+func cleanLabels(labels map[string]interface{}, removeLabels []string, changes *ChangeRecorder) {
 	if labels == nil {
 		return
 	}
-	for key := range labels {
-		for _, labelToRemove := range removeLabels {
-			if key == labelToRemove {
-				delete(labels, key)
-				break // Move to next key once a match is found
-			}
-		}
+	for _, labelToRemove := range removeLabels {
+		changes.remove(labels, labelToRemove, "cleanLabels:option")
 	}
 }
 
-// cleanAnnotations removes annotations matching specific prefixes and user provided annotations
-func cleanAnnotations(annotations map[string]interface{}, removeAnnotations []string) {
+// cleanAnnotations removes annotations matching specific prefixes and user provided annotations/prefixes
+func cleanAnnotations(annotations map[string]interface{}, removeAnnotations []string, extraPrefixes []string, changes *ChangeRecorder) {
 	if annotations == nil {
 		return
 	}
@@ -166,7 +165,6 @@ func cleanAnnotations(annotations map[string]interface{}, removeAnnotations []st
 		"statefulset.kubernetes.io/",
 		"service.kubernetes.io/",
 		"batch.kubernetes.io/",
-		"networking.k8s.io/",
 		"rbac.authorization.k8s.io/",
 		"argocd.argoproj.io/",
 		"helm.sh/",
@@ -183,43 +181,40 @@ func cleanAnnotations(annotations map[string]interface{}, removeAnnotations []st
 		"pod-template-hash":                        true, // Used by ReplicaSets (Deployments) - debatable, but often runtime
 	}
 
-	keysToDelete := []string{} // Collect keys to delete to avoid modifying map during iteration issues
+	keysToDelete := map[string]string{} // Key -> reason; collected to avoid modifying the map during iteration
 
 	for key := range annotations {
-		shouldDelete := false
-
 		// Check exact matches first
 		if annotationExactToRemove[key] {
-			shouldDelete = true
+			keysToDelete[key] = "cleanAnnotations:runtime"
+			continue
 		}
 
 		// Check prefixes
-		if !shouldDelete {
-			for _, prefix := range annotationPrefixesToRemove {
-				if strings.HasPrefix(key, prefix) {
-					shouldDelete = true
-					break
-				}
+		for _, prefix := range annotationPrefixesToRemove {
+			if strings.HasPrefix(key, prefix) {
+				keysToDelete[key] = "cleanAnnotations:runtime"
+				break
 			}
 		}
+		if _, found := keysToDelete[key]; found {
+			continue
+		}
 
-		// Check user-provided list
-		if !shouldDelete {
-			for _, annotationToRemove := range removeAnnotations {
-				if key == annotationToRemove {
-					shouldDelete = true
-					break
-				}
+		// Check user-provided prefixes and annotations
+		for _, prefix := range extraPrefixes {
+			if strings.HasPrefix(key, prefix) {
+				keysToDelete[key] = "cleanAnnotations:option"
+				break
 			}
 		}
-
-		if shouldDelete {
-			keysToDelete = append(keysToDelete, key)
+		if slices.Contains(removeAnnotations, key) {
+			keysToDelete[key] = "cleanAnnotations:option"
 		}
 	}
 
-	for _, key := range keysToDelete {
-		delete(annotations, key)
+	for _, key := range slices.Sorted(maps.Keys(keysToDelete)) {
+		changes.remove(annotations, key, keysToDelete[key])
 	}
 }
 
@@ -230,9 +225,12 @@ type GenericObjectCleaner struct {
 
 func (c *GenericObjectCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
 
+	// Classify the fields before anything is removed; nil for kinds without a schema
+	stateFields := schemasFor(options).stateFields(obj)
+
 	// --- State Preservation Handling (Run First) ---
 	if options.PreserveResourceState {
-		if stateFields, ok := resourceStateFields[obj.Kind]; ok {
+		if stateFields != nil {
 			fieldsToRemoveForState := []string{}
 			for fieldPath, isDesired := range stateFields {
 				remove := false
@@ -247,8 +245,9 @@ func (c *GenericObjectCleaner) Clean(obj *KubernetesObject, options *CleanupOpti
 				}
 			}
 			// Remove the identified fields
+			slices.Sort(fieldsToRemoveForState)
 			for _, fieldPath := range fieldsToRemoveForState {
-				removeField(obj, fieldPath)
+				removeField(obj, fieldPath, "GenericObjectCleaner:state")
 			}
 		}
 	}
@@ -261,13 +260,16 @@ func (c *GenericObjectCleaner) Clean(obj *KubernetesObject, options *CleanupOpti
 	// --- General Status Removal (Run After State Preservation) ---
 	// Only remove status generally if state preservation didn't already keep it.
 	isStatusRuntime := false
-	if stateFields, ok := resourceStateFields[obj.Kind]; ok {
-		if isDesired, exists := stateFields["status"]; exists && !isDesired {
-			isStatusRuntime = true
-		}
+	if isDesired, exists := stateFields["status"]; exists && !isDesired {
+		isStatusRuntime = true
 	}
 	if options.RemoveStatus && !(options.PreserveResourceState && options.ResourceStateMode == "Runtime" && isStatusRuntime) {
-		obj.Status = nil
+		removeField(obj, "status", "GenericObjectCleaner:runtime")
+	}
+
+	// --- Server-Side Defaults Removal ---
+	if shouldRemoveDefaults(options) {
+		removeDefaults(obj.Spec, kindSpecDefaults[obj.Kind], obj.Changes, "GenericObjectCleaner:default")
 	}
 
 	// --- ClusterName Removal (Placeholder) ---
@@ -278,12 +280,12 @@ func (c *GenericObjectCleaner) Clean(obj *KubernetesObject, options *CleanupOpti
 
 	// --- Final Empty Field Cleanup ---
 	if options.RemoveEmpty {
-		removeEmptyFields(obj)
+		removeEmptyFields(obj, obj.Changes)
 	}
 }
 
-// Helper to remove nested fields using dot notation
-func removeField(obj *KubernetesObject, fieldPath string) {
+// Helper to remove nested fields using dot notation. The removal is recorded with reason.
+func removeField(obj *KubernetesObject, fieldPath string, reason string) {
 	parts := strings.Split(fieldPath, ".")
 	if len(parts) == 0 {
 		return
@@ -291,6 +293,9 @@ func removeField(obj *KubernetesObject, fieldPath string) {
 
 	// Handle top-level fields directly
 	if len(parts) == 1 {
+		if value, exists := objectToMap(obj)[parts[0]]; exists {
+			obj.Changes.recordPath(appendKeyPath("", parts[0]), value, reason)
+		}
 		switch parts[0] {
 		case "metadata":
 			obj.Metadata = nil
@@ -304,6 +309,8 @@ func removeField(obj *KubernetesObject, fieldPath string) {
 			obj.StringData = nil
 		case "type":
 			obj.Type = "" // Reset type for secrets/services if needed
+		default:
+			delete(obj.Extra, parts[0]) // Unmodelled top-level field
 		}
 		return
 	}
@@ -322,7 +329,12 @@ func removeField(obj *KubernetesObject, fieldPath string) {
 	case "stringData":
 		currentMap = obj.StringData
 	default:
-		return // Cannot navigate path
+		// Unmodelled top-level field; only maps can be navigated
+		if extra, ok := obj.Extra[parts[0]].(map[string]interface{}); ok {
+			currentMap = extra
+		} else {
+			return // Cannot navigate path
+		}
 	}
 
 	if currentMap == nil {
@@ -338,12 +350,32 @@ func removeField(obj *KubernetesObject, fieldPath string) {
 	}
 
 	// Delete the final key
-	delete(currentMap, parts[len(parts)-1])
+	obj.Changes.remove(currentMap, parts[len(parts)-1], reason)
 }
 
 // removeEmptyFields recursively removes empty maps/slices and nil values.
 // It's called last to clean up anything left empty by previous steps.
-func removeEmptyFields(data interface{}) interface{} {
+// Removals are recorded in changes, including data itself when it becomes empty.
+func removeEmptyFields(data interface{}, changes *ChangeRecorder) interface{} {
+	path := ""
+	if m, ok := data.(map[string]interface{}); ok {
+		if m == nil {
+			return nil // An unset top-level field; nothing to record
+		}
+		path = changes.pathOf(m)
+	}
+	mark := changes.mark()
+	cleaned := removeEmptyFieldsAt(data, changes, path)
+	if cleaned == nil && data != nil && path != "" {
+		// Record the removal of data once rather than each of its empty children
+		changes.rollback(mark)
+		changes.recordPath(path, data, "removeEmptyFields:empty")
+	}
+	return cleaned
+}
+
+// removeEmptyFieldsAt implements removeEmptyFields for data located at path.
+func removeEmptyFieldsAt(data interface{}, changes *ChangeRecorder, path string) interface{} {
 	if data == nil {
 		return nil
 	}
@@ -361,16 +393,23 @@ func removeEmptyFields(data interface{}) interface{} {
 
 		// Try asserting to the expected map[string]interface{} first
 		if mapString, ok := value.Interface().(map[string]interface{}); ok {
-			for k, v := range mapString {
-				cleanedValue := removeEmptyFields(v)
+			for _, k := range sortedKeys(mapString) {
+				v := mapString[k]
+				childPath := appendKeyPath(path, k)
+				mark := changes.mark()
+				cleanedValue := removeEmptyFieldsAt(v, changes, childPath)
 				if cleanedValue != nil {
 					cleanedMap[k] = cleanedValue
 				} else if strVal, ok := v.(string); ok && strVal == "" {
 					cleanedMap[k] = "" // Keep intentional empty strings
+				} else {
+					changes.rollback(mark)
+					changes.recordPath(childPath, v, "removeEmptyFields:empty")
 				}
 			}
 		} else if mapInterface, ok := value.Interface().(map[interface{}]interface{}); ok {
 			// Handle the map[interface{}]interface{} case from yaml.v2 decoding
+			// (not recorded: normalized objects never contain these maps)
 			for k, v := range mapInterface {
 				// Attempt to convert key to string
 				stringKey, keyIsString := k.(string)
@@ -381,7 +420,7 @@ func removeEmptyFields(data interface{}) interface{} {
 					continue // Skip this key-value pair
 				}
 
-				cleanedValue := removeEmptyFields(v)
+				cleanedValue := removeEmptyFieldsAt(v, nil, "")
 				if cleanedValue != nil {
 					cleanedMap[stringKey] = cleanedValue
 				} else if strVal, ok := v.(string); ok && strVal == "" {
@@ -413,10 +452,15 @@ func removeEmptyFields(data interface{}) interface{} {
 		// Try asserting to []interface{}
 		if sliceValue, ok := value.Interface().([]interface{}); ok {
 			cleanedSlice := make([]interface{}, 0, len(sliceValue))
-			for _, item := range sliceValue {
-				cleanedItem := removeEmptyFields(item)
+			for i, item := range sliceValue {
+				itemPath := listItemPath(path, sliceValue, i)
+				mark := changes.mark()
+				cleanedItem := removeEmptyFieldsAt(item, changes, itemPath)
 				if cleanedItem != nil {
 					cleanedSlice = append(cleanedSlice, cleanedItem)
+				} else {
+					changes.rollback(mark)
+					changes.recordPath(itemPath, item, "removeEmptyFields:empty")
 				}
 			}
 			if len(cleanedSlice) == 0 {
@@ -439,7 +483,7 @@ func removeEmptyFields(data interface{}) interface{} {
 		if !elem.IsValid() {
 			return nil
 		}
-		return removeEmptyFields(elem.Interface())
+		return removeEmptyFieldsAt(elem.Interface(), changes, path)
 
 	default:
 		// Keep primitive types and non-empty strings
@@ -450,41 +494,122 @@ func removeEmptyFields(data interface{}) interface{} {
 // Helper function to apply removeEmptyFields to the top-level KubernetesObject fields
 // Handles potential nil maps after cleaning.
 func cleanupEmptyTopLevelFields(obj *KubernetesObject) {
-	cleanedMetadata := removeEmptyFields(obj.Metadata)
+	cleanedMetadata := removeEmptyFields(obj.Metadata, obj.Changes)
 	if cleanedMetadata == nil {
 		obj.Metadata = nil
 	} else if md, ok := cleanedMetadata.(map[string]interface{}); ok {
 		obj.Metadata = md
 	} // else: keep original if type assertion fails (shouldn't happen with correct input)
 
-	cleanedSpec := removeEmptyFields(obj.Spec)
+	cleanedSpec := removeEmptyFields(obj.Spec, obj.Changes)
 	if cleanedSpec == nil {
 		obj.Spec = nil
 	} else if sp, ok := cleanedSpec.(map[string]interface{}); ok {
 		obj.Spec = sp
 	}
 
-	cleanedStatus := removeEmptyFields(obj.Status)
+	cleanedStatus := removeEmptyFields(obj.Status, obj.Changes)
 	if cleanedStatus == nil {
 		obj.Status = nil
 	} else if st, ok := cleanedStatus.(map[string]interface{}); ok {
 		obj.Status = st
 	}
 
-	cleanedData := removeEmptyFields(obj.Data)
+	cleanedData := removeEmptyFields(obj.Data, obj.Changes)
 	if cleanedData == nil {
 		obj.Data = nil
 	} else if d, ok := cleanedData.(map[string]interface{}); ok {
 		obj.Data = d
 	}
 
-	cleanedStringData := removeEmptyFields(obj.StringData)
+	cleanedStringData := removeEmptyFields(obj.StringData, obj.Changes)
 	if cleanedStringData == nil {
 		obj.StringData = nil
 	} else if sd, ok := cleanedStringData.(map[string]interface{}); ok {
 		obj.StringData = sd
 	}
 	// Type is a string, handled by default case in removeEmptyFields if needed elsewhere
+	// Extra is deliberately left untouched: klean does not know whether empty values there are meaningful
+}
+
+// removeRuntimeStatus removes fields of a kept status that only make sense in the source cluster
+// (load balancer addresses, running Jobs, ...), unless the runtime state is preserved. The status
+// is removed when nothing else is left in it.
+func removeRuntimeStatus(obj *KubernetesObject, options *CleanupOptions, fields []string, cleaner string) {
+	if obj.Status == nil || (options.PreserveResourceState && options.ResourceStateMode == "Runtime") {
+		return
+	}
+	for _, field := range fields {
+		obj.Changes.remove(obj.Status, field, cleaner+":runtime")
+	}
+	if len(obj.Status) == 0 {
+		removeField(obj, "status", cleaner+":empty")
+	}
+}
+
+// normalizeValue recursively converts the map[interface{}]interface{} nodes produced by yaml.v2
+// into map[string]interface{} so that cleaners can rely on a consistent string-keyed tree.
+func normalizeValue(data interface{}) interface{} {
+	switch value := data.(type) {
+	case map[interface{}]interface{}:
+		normalized := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			stringKey, ok := k.(string)
+			if !ok {
+				// Kubernetes keys are always strings, but YAML allows e.g. `80: http`
+				stringKey = fmt.Sprint(k)
+			}
+			normalized[stringKey] = normalizeValue(v)
+		}
+		return normalized
+	case map[string]interface{}:
+		for k, v := range value {
+			value[k] = normalizeValue(v)
+		}
+		return value
+	case []interface{}:
+		for i, item := range value {
+			value[i] = normalizeValue(item)
+		}
+		return value
+	default:
+		return data
+	}
+}
+
+// normalizeMap applies normalizeValue to a top-level map field.
+func normalizeMap(data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	return normalizeValue(data).(map[string]interface{})
+}
+
+// normalizeObject normalizes every map held by a decoded KubernetesObject, including List items.
+func normalizeObject(obj *KubernetesObject) {
+	if obj == nil {
+		return
+	}
+	obj.Metadata = normalizeMap(obj.Metadata)
+	obj.Spec = normalizeMap(obj.Spec)
+	obj.Status = normalizeMap(obj.Status)
+	obj.Data = normalizeMap(obj.Data)
+	obj.StringData = normalizeMap(obj.StringData)
+	obj.Extra = normalizeMap(obj.Extra)
+	for i := range obj.Items {
+		normalizeObject(&obj.Items[i])
+	}
+}
+
+// cleanTemplateMetadata removes runtime fields and operational annotations from pod template metadata.
+func cleanTemplateMetadata(templateMeta map[string]interface{}, options *CleanupOptions, changes *ChangeRecorder) {
+	changes.remove(templateMeta, "creationTimestamp", "cleanTemplateMetadata:runtime")
+	if annotations, ok := templateMeta["annotations"].(map[string]interface{}); ok {
+		cleanAnnotations(annotations, options.RemoveAnnotations, options.ExtraAnnotationPrefixes, changes)
+		if len(annotations) == 0 {
+			changes.remove(templateMeta, "annotations", "cleanTemplateMetadata:empty")
+		}
+	}
 }
 
 // DeploymentCleaner cleans Deployment-specific fields.
@@ -496,26 +621,16 @@ func (c *DeploymentCleaner) Clean(obj *KubernetesObject, options *CleanupOptions
 	c.genericCleaner.Clean(obj, options) // Clean generic fields first
 
 	if obj.Spec != nil {
-		// Remove fields not typically needed for desired state definition
-		// State preservation logic in genericCleaner handles replicas/strategy/template if enabled
-		if !(options.PreserveResourceState && options.ResourceStateMode == "Runtime") {
-			// Only remove these if not preserving runtime state
-			delete(obj.Spec, "revisionHistoryLimit")
-			delete(obj.Spec, "progressDeadlineSeconds")
-			// selector is often desired state, keep it unless preserving runtime
-		}
-
+		// Defaulted fields (revisionHistoryLimit, strategy, ...) are removed by the generic cleaner
 		if template, ok := obj.Spec["template"].(map[string]interface{}); ok {
 			// Clean metadata within the template
 			if templateMeta, ok := template["metadata"].(map[string]interface{}); ok {
 				// Remove runtime fields specifically from template metadata
-				delete(templateMeta, "creationTimestamp")
-				// Clean labels/annotations within template metadata if needed (optional)
-				// cleanAnnotations(templateMeta["annotations"]...)
-				// cleanLabels(templateMeta["labels"]...)
+				cleanTemplateMetadata(templateMeta, options, obj.Changes)
+				// Template labels are left alone: they must keep matching spec.selector
 
 				// Remove template metadata only if it becomes completely empty after cleaning
-				cleanedTemplateMeta := removeEmptyFields(templateMeta)
+				cleanedTemplateMeta := removeEmptyFields(templateMeta, obj.Changes)
 				if cleanedTemplateMeta == nil {
 					delete(template, "metadata")
 				} else if tm, ok := cleanedTemplateMeta.(map[string]interface{}); ok {
@@ -524,9 +639,9 @@ func (c *DeploymentCleaner) Clean(obj *KubernetesObject, options *CleanupOptions
 			}
 			// Clean the pod spec within the template
 			if spec, ok := template["spec"].(map[string]interface{}); ok {
-				cleanPodSpec(spec, options)
+				cleanPodSpec(spec, options, obj.Changes)
 				// Remove template spec only if it becomes completely empty
-				cleanedSpec := removeEmptyFields(spec)
+				cleanedSpec := removeEmptyFields(spec, obj.Changes)
 				if cleanedSpec == nil {
 					delete(template, "spec") // Should not happen for valid template
 				} else if sp, ok := cleanedSpec.(map[string]interface{}); ok {
@@ -558,36 +673,15 @@ func (c *ServiceCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
 		}
 		if !(options.PreserveResourceState && options.ResourceStateMode == "Desired") {
 			// Only remove clusterIP(s) if not preserving desired state (they are runtime)
-			delete(obj.Spec, "clusterIP")
-			delete(obj.Spec, "clusterIPs")
-			delete(obj.Spec, "ipFamilies")            // Runtime assigned
-			delete(obj.Spec, "ipFamilyPolicy")        // Runtime assigned
-			delete(obj.Spec, "internalTrafficPolicy") // Often defaulted/runtime
+			obj.Changes.remove(obj.Spec, "clusterIP", "ServiceCleaner:runtime")
+			obj.Changes.remove(obj.Spec, "clusterIPs", "ServiceCleaner:runtime")
+			obj.Changes.remove(obj.Spec, "ipFamilies", "ServiceCleaner:runtime") // Runtime assigned
+			// ipFamilyPolicy and internalTrafficPolicy are only removed when defaulted
 		}
 
-		// Clean ports: Remove default protocol TCP
-		if ports, ok := obj.Spec["ports"].([]interface{}); ok {
-			cleanedPorts := make([]interface{}, 0, len(ports))
-			for _, p := range ports {
-				if portMap, ok := p.(map[string]interface{}); ok {
-					if proto, exists := portMap["protocol"]; exists {
-						if protoStr, ok := proto.(string); ok && strings.ToUpper(protoStr) == "TCP" {
-							delete(portMap, "protocol") // Remove default protocol
-						}
-					}
-					// Keep port even if protocol was removed, unless port itself is empty
-					if len(portMap) > 0 {
-						cleanedPorts = append(cleanedPorts, portMap)
-					}
-				} else {
-					cleanedPorts = append(cleanedPorts, p) // Keep non-map items if any
-				}
-			}
-			if len(cleanedPorts) > 0 {
-				obj.Spec["ports"] = cleanedPorts
-			} else {
-				delete(obj.Spec, "ports") // Remove if ports list becomes empty
-			}
+		// Clean ports: Remove default protocol TCP and targetPort equal to port
+		if shouldRemoveDefaults(options) {
+			removeListDefaults(obj.Spec, "ports", servicePortDefaults, obj.Changes, "ServiceCleaner:default")
 		}
 	}
 	// Final cleanup of empty fields
@@ -605,17 +699,11 @@ func (c *StatefulSetCleaner) Clean(obj *KubernetesObject, options *CleanupOption
 	c.genericCleaner.Clean(obj, options)
 
 	if obj.Spec != nil {
-		// Remove fields not typically needed for desired state definition
-		// State preservation handles replicas/updateStrategy/template
-		if !(options.PreserveResourceState && options.ResourceStateMode == "Runtime") {
-			delete(obj.Spec, "revisionHistoryLimit")
-			// selector is desired state
-		}
-
+		// Defaulted fields (revisionHistoryLimit, updateStrategy, ...) are removed by the generic cleaner
 		if template, ok := obj.Spec["template"].(map[string]interface{}); ok {
 			if templateMeta, ok := template["metadata"].(map[string]interface{}); ok {
-				delete(templateMeta, "creationTimestamp")
-				cleanedTemplateMeta := removeEmptyFields(templateMeta)
+				cleanTemplateMetadata(templateMeta, options, obj.Changes)
+				cleanedTemplateMeta := removeEmptyFields(templateMeta, obj.Changes)
 				if cleanedTemplateMeta == nil {
 					delete(template, "metadata")
 				} else if tm, ok := cleanedTemplateMeta.(map[string]interface{}); ok {
@@ -623,8 +711,8 @@ func (c *StatefulSetCleaner) Clean(obj *KubernetesObject, options *CleanupOption
 				}
 			}
 			if spec, ok := template["spec"].(map[string]interface{}); ok {
-				cleanPodSpec(spec, options)
-				cleanedSpec := removeEmptyFields(spec)
+				cleanPodSpec(spec, options, obj.Changes)
+				cleanedSpec := removeEmptyFields(spec, obj.Changes)
 				if cleanedSpec == nil {
 					delete(template, "spec")
 				} else if sp, ok := cleanedSpec.(map[string]interface{}); ok {
@@ -647,17 +735,11 @@ type DaemonSetCleaner struct {
 func (c *DaemonSetCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
 	c.genericCleaner.Clean(obj, options)
 	if obj.Spec != nil {
-		// Remove fields not typically needed for desired state definition
-		// State preservation handles updateStrategy/template
-		if !(options.PreserveResourceState && options.ResourceStateMode == "Runtime") {
-			delete(obj.Spec, "revisionHistoryLimit")
-			// selector is desired state
-		}
-
+		// Defaulted fields (revisionHistoryLimit, updateStrategy, ...) are removed by the generic cleaner
 		if template, ok := obj.Spec["template"].(map[string]interface{}); ok {
 			if templateMeta, ok := template["metadata"].(map[string]interface{}); ok {
-				delete(templateMeta, "creationTimestamp")
-				cleanedTemplateMeta := removeEmptyFields(templateMeta)
+				cleanTemplateMetadata(templateMeta, options, obj.Changes)
+				cleanedTemplateMeta := removeEmptyFields(templateMeta, obj.Changes)
 				if cleanedTemplateMeta == nil {
 					delete(template, "metadata")
 				} else if tm, ok := cleanedTemplateMeta.(map[string]interface{}); ok {
@@ -665,8 +747,8 @@ func (c *DaemonSetCleaner) Clean(obj *KubernetesObject, options *CleanupOptions)
 				}
 			}
 			if spec, ok := template["spec"].(map[string]interface{}); ok {
-				cleanPodSpec(spec, options)
-				cleanedSpec := removeEmptyFields(spec)
+				cleanPodSpec(spec, options, obj.Changes)
+				cleanedSpec := removeEmptyFields(spec, obj.Changes)
 				if cleanedSpec == nil {
 					delete(template, "spec")
 				} else if sp, ok := cleanedSpec.(map[string]interface{}); ok {
@@ -683,37 +765,33 @@ func (c *DaemonSetCleaner) Clean(obj *KubernetesObject, options *CleanupOptions)
 
 // PodCleaner cleans Pod-specific fields.
 type PodCleaner struct {
-	genericCleaner ObjectCleaner // Use interface type
+	genericCleaner ObjectCleaner         // Use interface type
+	factory        *ObjectCleanerFactory // Cleans the controllers Pods are reverted to
 }
 
 func (c *PodCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
-	// Attempt revert *before* generic cleaning, as generic cleaning might remove labels needed for revert
+	// Attempt revert *before* generic cleaning, as generic cleaning removes the ownerReferences and labels needed for revert
 	if options.RevertToDeployment {
-		reverted := revertPodToDeployment(obj) // revertPodToDeployment now returns bool
-		if reverted {
-			// If reverted, get the Deployment cleaner and clean *that* object instead
-			// This assumes the factory is accessible or passed down. For simplicity here,
-			// we'll just re-apply generic cleaning. A better approach might involve
-			// the factory pattern more deeply.
-			log.Println("Reverted Pod to Deployment, re-applying generic cleaning")
-			// Re-apply generic cleaning to the *new* Deployment object structure
-			c.genericCleaner.Clean(obj, options)
+		if controller, reverted := reconstructController(obj); reverted {
+			// Several Pods of the same controller become a single controller
+			key := controller.key(obj.namespace)
+			if c.factory.reconstructed[key] {
+				log.Printf("Dropping Pod of %s '%s': the controller was already reconstructed from another Pod", controller.kind, controller.name)
+				obj.dropped = "PodCleaner:duplicate"
+				return
+			}
+			c.factory.reconstructed[key] = true
 
-			// Specifically clean the pod spec *within* the new template
-			if obj.Spec != nil {
-				if template, ok := obj.Spec["template"].(map[string]interface{}); ok {
-					if spec, ok := template["spec"].(map[string]interface{}); ok {
-						cleanPodSpec(spec, options) // Clean the spec moved into the template
-						cleanedSpec := removeEmptyFields(spec)
-						if cleanedSpec == nil {
-							delete(template, "spec")
-						} else if sp, ok := cleanedSpec.(map[string]interface{}); ok {
-							template["spec"] = sp
-						}
-					}
-				}
+			// Clean the reconstructed controller with its own cleaner
+			cleaner := c.factory.cleaners[obj.Kind]
+			if cleaner != nil {
+				cleaner.Clean(obj, options)
+				return
+			}
+			c.genericCleaner.Clean(obj, options)
+			if template := podTemplateOf(obj); template != nil {
+				cleanPodTemplate(template, options, obj.Changes)
 			}
-			// Final cleanup of empty fields for the Deployment
 			if options.RemoveEmpty {
 				cleanupEmptyTopLevelFields(obj)
 			}
@@ -724,9 +802,9 @@ func (c *PodCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
 	// If not reverted, proceed with standard Pod cleaning
 	c.genericCleaner.Clean(obj, options)
 	if obj.Spec != nil {
-		cleanPodSpec(obj.Spec, options)
+		cleanPodSpec(obj.Spec, options, obj.Changes)
 		// Clean the top-level spec itself if it becomes empty
-		cleanedSpec := removeEmptyFields(obj.Spec)
+		cleanedSpec := removeEmptyFields(obj.Spec, obj.Changes)
 		if cleanedSpec == nil {
 			obj.Spec = nil
 		} else if sp, ok := cleanedSpec.(map[string]interface{}); ok {
@@ -747,9 +825,9 @@ type ConfigMapCleaner struct {
 func (c *ConfigMapCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
 	c.genericCleaner.Clean(obj, options)
 	if obj.Data != nil {
-		cleanConfigMapData(obj.Data)
+		cleanConfigMapData(obj.Data, obj.Changes)
 		if len(obj.Data) == 0 {
-			obj.Data = nil // Remove data field if empty
+			removeField(obj, "data", "ConfigMapCleaner:empty") // Remove data field if empty
 		}
 	}
 	// Final cleanup of empty fields
@@ -759,7 +837,7 @@ func (c *ConfigMapCleaner) Clean(obj *KubernetesObject, options *CleanupOptions)
 }
 
 // cleanConfigMapData removes specific noisy keys often found in ConfigMaps
-func cleanConfigMapData(data map[string]interface{}) {
+func cleanConfigMapData(data map[string]interface{}, changes *ChangeRecorder) {
 	keysToDelete := []string{}
 	for key := range data {
 		// Remove keys commonly holding last applied configuration or similar metadata
@@ -771,7 +849,7 @@ func cleanConfigMapData(data map[string]interface{}) {
 		// if key == "ca.crt" && len(data) == 1 { ... }
 	}
 	for _, key := range keysToDelete {
-		delete(data, key)
+		changes.remove(data, key, "cleanConfigMapData:runtime")
 	}
 }
 
@@ -781,7 +859,6 @@ type SecretCleaner struct {
 }
 
 func (c *SecretCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
-	c.genericCleaner.Clean(obj, options)
 	// Secrets often contain service account tokens or docker config generated at runtime.
 	// We might want to remove specific types or data keys.
 
@@ -791,14 +868,20 @@ func (c *SecretCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
 		secretName = name
 	}
 
-	// Remove common runtime-generated secrets entirely? (Potentially dangerous, make optional?)
-	// Example: Remove default service account tokens
-	if obj.Type == "kubernetes.io/service-account-token" && strings.HasPrefix(secretName, "default-token-") {
-		log.Printf("Note: Secret '%s' looks like a default service account token. Consider removing manually if not needed.", secretName)
-		// To actually remove: obj.Data = nil; obj.StringData = nil; obj.Type = ""
-		// Or maybe set a flag to skip encoding this object entirely?
+	// Token Secrets generated for a ServiceAccount are created again by the token controller (and
+	// hold a token signed by the source cluster); hand-made token Secrets are kept
+	if obj.Type == "kubernetes.io/service-account-token" {
+		annotations, _ := obj.Metadata["annotations"].(map[string]interface{})
+		account, _ := annotations["kubernetes.io/service-account.name"].(string)
+		if account != "" && isGeneratedSecretName(account, secretName) {
+			log.Printf("Dropping Secret '%s': token generated for ServiceAccount '%s'", secretName, account)
+			obj.dropped = "SecretCleaner:runtime"
+			return
+		}
 	}
 
+	c.genericCleaner.Clean(obj, options)
+
 	// Example: Clean docker config secrets?
 	if obj.Type == "kubernetes.io/dockerconfigjson" {
 		// Maybe remove specific keys from .dockerconfigjson if needed?
@@ -806,10 +889,10 @@ func (c *SecretCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
 
 	// Clean potentially empty data/stringData after generic cleaning
 	if obj.Data != nil && len(obj.Data) == 0 {
-		obj.Data = nil
+		removeField(obj, "data", "SecretCleaner:empty")
 	}
 	if obj.StringData != nil && len(obj.StringData) == 0 {
-		obj.StringData = nil
+		removeField(obj, "stringData", "SecretCleaner:empty")
 	}
 	// Final cleanup of empty fields
 	if options.RemoveEmpty {
@@ -818,7 +901,7 @@ func (c *SecretCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
 }
 
 // cleanPodSpec removes fields from Pod specs (used for Pods and templates).
-func cleanPodSpec(spec map[string]interface{}, options *CleanupOptions) {
+func cleanPodSpec(spec map[string]interface{}, options *CleanupOptions, changes *ChangeRecorder) {
 	if spec == nil {
 		return
 	}
@@ -828,60 +911,41 @@ func cleanPodSpec(spec map[string]interface{}, options *CleanupOptions) {
 		// "serviceAccountName", // Often desired state
 		// "serviceAccount", // Older field, less common
 		// "automountServiceAccountToken", // Can be desired state
-		"dnsPolicy", // Often defaulted
 		// "nodeSelector", // Often desired state
 		// "tolerations", // Often desired state
 		// "affinity", // Often desired state
-		"schedulerName",
 		// "priorityClassName", // Often desired state
 		// "priority", // Often desired state
-		"enableServiceLinks", // Often defaulted
-		"preemptionPolicy",
-		// "restartPolicy", // Usually implied by controller
-		"terminationGracePeriodSeconds", // Often defaulted
-		"hostIP",                        // Runtime
-		"podIP",                         // Runtime
-		"podIPs",                        // Runtime
-		"hostPID",                       // Runtime/Security Context related
-		"hostNetwork",                   // Runtime/Security Context related
-		"hostIPC",                       // Runtime/Security Context related
-		"hostname",                      // Runtime/Set by system
-		"subdomain",                     // Runtime/Set by system
-		"shareProcessNamespace",         // Runtime/Security Context related
-		"runtimeClassName",              // Runtime/Node specific
-		"readinessGates",                // Often status related
+		"hostIP",           // Runtime
+		"podIP",            // Runtime
+		"podIPs",           // Runtime
+		"hostname",         // Runtime/Set by system
+		"subdomain",        // Runtime/Set by system
+		"runtimeClassName", // Runtime/Node specific
+		"readinessGates",   // Often status related
 		// "topologySpreadConstraints", // Often desired state
-		"setHostnameAsFQDN", // Often defaulted
+		// Defaulted fields (dnsPolicy, schedulerName, terminationGracePeriodSeconds, ...) are only
+		// removed when they hold their default value, see podSpecDefaults
 	}
 
 	// Conditionally remove based on state preservation
 	if options.PreserveResourceState {
-		podStateFields, podStateOk := resourceStateFields["Pod"]
+		schemas := schemasFor(options)
 		tempRemoveList := []string{}
 		for _, field := range fieldsToRemove {
-			fieldPath := "spec." + field // Construct path for lookup
-
 			remove := true // Default to removing these runtime/defaulted fields
 
-			if podStateOk {
-				isDesired, exists := podStateFields[fieldPath]
-				if exists { // If defined in state map
-					if options.ResourceStateMode == "Desired" && isDesired {
-						remove = false // Keep desired field when preserving desired
-					} else if options.ResourceStateMode == "Runtime" && !isDesired {
-						remove = false // Keep runtime field when preserving runtime
-					}
-					// If field exists in map but doesn't match preservation mode, 'remove' remains true
-				} else {
-					// If not in state map, assume runtime/defaulted.
-					// Keep it only if preserving runtime state.
-					if options.ResourceStateMode == "Runtime" {
-						remove = false
-					}
+			isDesired, exists := schemas.podSpecFieldState(field)
+			if exists { // If described by the PodSpec schema
+				if options.ResourceStateMode == "Desired" && isDesired {
+					remove = false // Keep desired field when preserving desired
+				} else if options.ResourceStateMode == "Runtime" && !isDesired {
+					remove = false // Keep runtime field when preserving runtime
 				}
+				// If field exists in the schema but doesn't match preservation mode, 'remove' remains true
 			} else {
-				// If "Pod" kind is missing from state map entirely,
-				// fall back to default behavior: remove if preserving desired, keep if preserving runtime.
+				// If not in the schema, assume runtime/defaulted.
+				// Keep it only if preserving runtime state.
 				if options.ResourceStateMode == "Runtime" {
 					remove = false
 				}
@@ -893,22 +957,32 @@ func cleanPodSpec(spec map[string]interface{}, options *CleanupOptions) {
 		}
 		fieldsToRemove = tempRemoveList
 	}
-
 	for _, field := range fieldsToRemove {
-		delete(spec, field)
+		changes.remove(spec, field, "cleanPodSpec:runtime")
+	}
+	// User-configured fields are always removed
+	for _, field := range options.ExtraPodFields {
+		changes.remove(spec, field, "cleanPodSpec:option")
+	}
+	if shouldRemoveDefaults(options) {
+		removeDefaults(spec, podSpecDefaults, changes, "cleanPodSpec:default")
+		removeListDefaults(spec, "volumes", volumeDefaults, changes, "cleanPodSpec:default")
+		removeDefaultTolerations(spec, changes, "cleanPodSpec:default")
 	}
 
 	// Clean containers and initContainers
 	for _, containerType := range []string{"containers", "initContainers"} {
 		if containers, ok := spec[containerType].([]interface{}); ok {
 			cleanedContainers := make([]interface{}, 0, len(containers))
-			for _, container := range containers {
+			for i, container := range containers {
 				if containerMap, ok := container.(map[string]interface{}); ok {
-					cleanContainerSpec(containerMap, options)
+					cleanContainerSpec(containerMap, options, changes)
 					// Keep container even if empty after cleaning? Usually name/image remain.
 					// Only discard if the map becomes truly empty (unlikely for valid container)
 					if len(containerMap) > 0 {
 						cleanedContainers = append(cleanedContainers, containerMap)
+					} else {
+						changes.recordItem(spec, containerType, containers, i, "cleanPodSpec:empty")
 					}
 				} else {
 					cleanedContainers = append(cleanedContainers, container) // Keep non-map items
@@ -923,69 +997,42 @@ func cleanPodSpec(spec map[string]interface{}, options *CleanupOptions) {
 	}
 
 	// Clean volumes and associated volumeMounts (modifies spec in place)
-	cleanPodVolumes(spec)
+	cleanPodVolumes(spec, changes)
 
 	// Remove empty volumes list if necessary (after cleanPodVolumes)
 	if volumes, ok := spec["volumes"].([]interface{}); ok && len(volumes) == 0 {
-		delete(spec, "volumes")
+		changes.remove(spec, "volumes", "cleanPodSpec:empty")
 	}
 }
 
 // cleanContainerSpec removes fields from container specs.
-func cleanContainerSpec(container map[string]interface{}, options *CleanupOptions) {
+func cleanContainerSpec(container map[string]interface{}, options *CleanupOptions, changes *ChangeRecorder) {
 	if container == nil {
 		return
 	}
-	// Fields typically representing runtime state, defaults, or status probes
-	fieldsToRemove := []string{
-		"terminationMessagePath",   // Defaulted
-		"terminationMessagePolicy", // Defaulted
-		"imagePullPolicy",          // Defaulted or runtime decision
-		// "securityContext",       // Often desired state
-		// "livenessProbe",         // Often desired state
-		// "readinessProbe",        // Often desired state
-		// "startupProbe",          // Often desired state
-		// "resources",             // Often desired state (requests/limits)
-		"tty",       // Runtime interaction hint
-		"stdin",     // Runtime interaction hint
-		"stdinOnce", // Runtime interaction hint
-	}
+	// Defaulted fields (terminationMessagePath, imagePullPolicy, tty, ...) are only removed when
+	// they hold their default value, see containerDefaults.
 	// Note: We generally KEEP 'name', 'image', 'command', 'args', 'ports', 'env', 'envFrom', 'volumeMounts' as core desired state.
-
-	for _, field := range fieldsToRemove {
-		delete(container, field)
+	for _, field := range options.ExtraContainerFields {
+		changes.remove(container, field, "cleanContainerSpec:option")
 	}
 
-	// Clean ports: Remove default protocol TCP
-	if ports, ok := container["ports"].([]interface{}); ok {
-		cleanedPorts := make([]interface{}, 0, len(ports))
-		for _, p := range ports {
-			if portMap, ok := p.(map[string]interface{}); ok {
-				if proto, exists := portMap["protocol"]; exists {
-					if protoStr, ok := proto.(string); ok && strings.ToUpper(protoStr) == "TCP" {
-						delete(portMap, "protocol") // Remove default protocol
-					}
-				}
-				// Keep port even if protocol was removed, unless port itself is empty
-				if len(portMap) > 0 {
-					cleanedPorts = append(cleanedPorts, portMap)
-				}
-			} else {
-				cleanedPorts = append(cleanedPorts, p) // Keep non-map items
+	if shouldRemoveDefaults(options) {
+		removeDefaults(container, containerDefaults, changes, "cleanContainerSpec:default")
+		// Clean ports: Remove default protocol TCP
+		removeListDefaults(container, "ports", containerPortDefaults, changes, "cleanContainerSpec:default")
+		for _, probe := range []string{"livenessProbe", "readinessProbe", "startupProbe"} {
+			if probeMap, ok := container[probe].(map[string]interface{}); ok {
+				removeDefaults(probeMap, probeDefaults, changes, "cleanContainerSpec:default")
 			}
 		}
-		if len(cleanedPorts) > 0 {
-			container["ports"] = cleanedPorts
-		} else {
-			delete(container, "ports") // Remove if ports list becomes empty
-		}
 	}
 
 	// Clean volumeMounts (handled by cleanPodVolumes called from cleanPodSpec)
 }
 
 // cleanPodVolumes removes kube-api-access volumes and related volumeMounts
-func cleanPodVolumes(spec map[string]interface{}) {
+func cleanPodVolumes(spec map[string]interface{}, changes *ChangeRecorder) {
 	if spec == nil {
 		return
 	}
@@ -994,7 +1041,7 @@ func cleanPodVolumes(spec map[string]interface{}) {
 	// Identify volumes to remove (e.g., kube-api-access, projected service account tokens)
 	if volumes, ok := spec["volumes"].([]interface{}); ok {
 		cleanedVolumes := make([]interface{}, 0, len(volumes))
-		for _, volume := range volumes {
+		for i, volume := range volumes {
 			shouldKeep := true
 			if volumeMap, ok := volume.(map[string]interface{}); ok {
 				// Check name for kube-api-access prefix
@@ -1027,9 +1074,11 @@ func cleanPodVolumes(spec map[string]interface{}) {
 			// Keep the volume if it wasn't marked for removal
 			if shouldKeep {
 				cleanedVolumes = append(cleanedVolumes, volume)
+			} else {
+				changes.recordItem(spec, "volumes", volumes, i, "cleanPodVolumes:runtime")
 			}
 		}
-		// Update spec with the cleaned list or remove if empty
+		// Update spec with the cleaned list or remove if empty (the removed volumes are recorded)
 		if len(cleanedVolumes) > 0 {
 			spec["volumes"] = cleanedVolumes
 		} else {
@@ -1049,7 +1098,7 @@ func cleanPodVolumes(spec map[string]interface{}) {
 				if containerMap, ok := container.(map[string]interface{}); ok {
 					if volumeMounts, exists := containerMap["volumeMounts"].([]interface{}); exists {
 						cleanedVolumeMounts := make([]interface{}, 0, len(volumeMounts))
-						for _, vm := range volumeMounts {
+						for i, vm := range volumeMounts {
 							shouldKeepMount := true
 							if vmMap, ok := vm.(map[string]interface{}); ok {
 								if name, nameExists := vmMap["name"].(string); nameExists {
@@ -1060,6 +1109,8 @@ func cleanPodVolumes(spec map[string]interface{}) {
 							}
 							if shouldKeepMount {
 								cleanedVolumeMounts = append(cleanedVolumeMounts, vm)
+							} else {
+								changes.recordItem(containerMap, "volumeMounts", volumeMounts, i, "cleanPodVolumes:runtime")
 							}
 						}
 						// Update or remove volumeMounts list in the container
@@ -1075,111 +1126,6 @@ func cleanPodVolumes(spec map[string]interface{}) {
 	}
 }
 
-// revertPodToDeployment attempts to reconstruct a Deployment from a Pod. Returns true if successful.
-func revertPodToDeployment(obj *KubernetesObject) bool {
-	if obj == nil || obj.Kind != "Pod" || obj.Metadata == nil {
-		return false // Only process valid Pods
-	}
-
-	// Check for the presence of a pod-template-hash label.
-	podLabels, labelsOk := obj.Metadata["labels"].(map[string]interface{})
-	if !labelsOk {
-		log.Printf("Skipping Pod revert for '%s': No labels found.", obj.Metadata["name"])
-		return false // No labels found
-	}
-
-	hashValue, hasHash := podLabels["pod-template-hash"]
-	if !hasHash {
-		log.Printf("Skipping Pod revert for '%s': Missing 'pod-template-hash' label.", obj.Metadata["name"])
-		return false // Not controlled by a standard controller using this label
-	}
-	hashStr, hashOk := hashValue.(string)
-	if !hashOk || hashStr == "" {
-		log.Printf("Skipping Pod revert for '%s': Invalid 'pod-template-hash' label value.", obj.Metadata["name"])
-		return false // Invalid hash label value
-	}
-
-	// --- Construct Deployment ---
-	log.Printf("Attempting to revert Pod '%s' to Deployment based on pod-template-hash '%s'", obj.Metadata["name"], hashStr)
-
-	// Preserve original metadata fields selectively
-	originalName := obj.Metadata["name"] // Might need adjustment (e.g., remove hash suffix)
-	originalNamespace := obj.Metadata["namespace"]
-
-	// Attempt to derive a base name for the Deployment
-	deploymentName := fmt.Sprintf("%s-reverted", originalName) // Default name
-	if baseName, ok := deriveBaseName(originalName.(string), hashStr); ok {
-		deploymentName = baseName
-	} else {
-		log.Printf("Warning: Could not derive base name for Deployment from Pod name '%s'. Using default.", originalName)
-	}
-
-	// Copy all original labels for the deployment itself, EXCLUDING pod-template-hash
-	deploymentLabels := make(map[string]interface{})
-	for k, v := range podLabels {
-		if k != "pod-template-hash" {
-			deploymentLabels[k] = v
-		}
-	}
-	// If no labels remain, maybe add a default one?
-	if len(deploymentLabels) == 0 {
-		deploymentLabels["app"] = deploymentName // Example default label
-	}
-
-	// Template labels should match deployment labels (or be derived appropriately)
-	templateLabels := make(map[string]interface{})
-	for k, v := range deploymentLabels {
-		templateLabels[k] = v
-	}
-	// Add the pod-template-hash back to the *template* labels if desired?
-	// Usually selector matches template labels, so maybe not needed here.
-
-	// Create the Deployment structure
-	obj.APIVersion = "apps/v1"
-	obj.Kind = "Deployment"
-
-	// Reset Metadata, keeping essential parts
-	obj.Metadata = map[string]interface{}{
-		"name":   deploymentName,
-		"labels": deploymentLabels,
-	}
-	if originalNamespace != nil {
-		obj.Metadata["namespace"] = originalNamespace
-	}
-	// Remove pod-specific metadata fields that don't apply to Deployments
-	delete(obj.Metadata, "generateName")
-	// Keep annotations? Maybe clean them separately.
-
-	// Preserve original Pod Spec
-	originalPodSpec := obj.Spec // Keep a reference before overwriting obj.Spec
-
-	// Create Deployment Spec
-	obj.Spec = map[string]interface{}{
-		"replicas": 1, // Default to 1 replica
-		"selector": map[string]interface{}{
-			// Selector should match the labels applied to the *template*
-			"matchLabels": templateLabels,
-		},
-		"template": map[string]interface{}{
-			"metadata": map[string]interface{}{
-				"labels": templateLabels, // Apply derived labels to template
-			},
-			"spec": originalPodSpec, // Move the original Pod's spec here
-		},
-		// Add default strategy?
-		// "strategy": map[string]interface{}{"type": "RollingUpdate", ...},
-	}
-
-	// Clear Status and other Pod-specific top-level fields
-	obj.Status = nil
-	obj.Data = nil
-	obj.StringData = nil
-	obj.Type = ""
-
-	log.Printf("Successfully reverted Pod '%s' to Deployment structure named '%s'", originalName, deploymentName)
-	return true
-}
-
 // deriveBaseName attempts to remove common controller hash suffixes from a pod name.
 func deriveBaseName(podName, hash string) (string, bool) {
 	// Common pattern: deployment-name-<pod-template-hash>-<random-suffix>
@@ -1204,7 +1150,10 @@ func deriveBaseName(podName, hash string) (string, bool) {
 
 // ObjectCleanerFactory maps kinds to cleaners.
 type ObjectCleanerFactory struct {
-	cleaners map[string]ObjectCleaner
+	cleaners      map[string]ObjectCleaner
+	reconstructed map[string]bool // Controllers reconstructed from Pods, see podController.key
+	owners        *ownerIndex     // The objects of the manifest, to drop the children of controllers in it
+	writer        *documentWriter // The writer of the manifest, to render objects for the report
 }
 
 // GetCleaner returns the appropriate cleaner for the given kind.
@@ -1235,13 +1184,34 @@ func NewObjectCleanerFactory() *ObjectCleanerFactory {
 			"Service":     &ServiceCleaner{genericCleaner: genericObjCleaner},
 			"StatefulSet": &StatefulSetCleaner{genericCleaner: genericObjCleaner},
 			"DaemonSet":   &DaemonSetCleaner{genericCleaner: genericObjCleaner},
-			"Pod":         &PodCleaner{genericCleaner: genericObjCleaner},
 			"ConfigMap":   &ConfigMapCleaner{genericCleaner: genericObjCleaner},
 			"Secret":      &SecretCleaner{genericCleaner: genericObjCleaner},
+			"Job":         &JobCleaner{genericCleaner: genericObjCleaner},
+			"CronJob":     &CronJobCleaner{genericCleaner: genericObjCleaner},
+
+			"PersistentVolumeClaim": &PersistentVolumeClaimCleaner{genericCleaner: genericObjCleaner},
+			"PersistentVolume":      &PersistentVolumeCleaner{genericCleaner: genericObjCleaner},
+
+			"Ingress":       &IngressCleaner{genericCleaner: genericObjCleaner},
+			"IngressClass":  &IngressClassCleaner{genericCleaner: genericObjCleaner},
+			"NetworkPolicy": &NetworkPolicyCleaner{genericCleaner: genericObjCleaner},
+			"Gateway":       &GatewayCleaner{genericCleaner: genericObjCleaner},
+			"HTTPRoute":     &HTTPRouteCleaner{genericCleaner: genericObjCleaner},
+
+			"ServiceAccount":     &ServiceAccountCleaner{genericCleaner: genericObjCleaner},
+			"Role":               &RBACCleaner{genericCleaner: genericObjCleaner},
+			"ClusterRole":        &RBACCleaner{genericCleaner: genericObjCleaner},
+			"RoleBinding":        &RBACCleaner{genericCleaner: genericObjCleaner},
+			"ClusterRoleBinding": &RBACCleaner{genericCleaner: genericObjCleaner},
+
+			"HorizontalPodAutoscaler": &HorizontalPodAutoscalerCleaner{genericCleaner: genericObjCleaner},
+			"PodDisruptionBudget":     &PodDisruptionBudgetCleaner{genericCleaner: genericObjCleaner},
 			// Add more cleaners for other kinds as needed.
 			// Example: "ReplicaSet": &ReplicaSetCleaner{genericCleaner: genericObjCleaner},
 		},
+		reconstructed: map[string]bool{},
 	}
+	factory.cleaners["Pod"] = &PodCleaner{genericCleaner: genericObjCleaner, factory: factory}
 	return factory
 }
 
@@ -1252,60 +1222,197 @@ func cleanupKubernetesObject(obj *KubernetesObject, options *CleanupOptions, cle
 		return // Cannot determine cleaner without Kind
 	}
 
+	// Cleaners type-assert on map[string]interface{}; make sure nested yaml.v2 maps match
+	normalizeObject(obj)
+	obj.namespace, _ = obj.Metadata["namespace"].(string)
+
+	// Apply per-kind overrides (e.g. from a cleanup profile) before dispatching
+	options = options.forKind(obj.Kind)
+
+	// Snapshot the object and record its changes for the report; nil when reporting is disabled
+	report := options.Report.begin(obj, cleanerFactory.writer)
+
+	// Children whose controller is part of the input are recreated by it
+	if options.CollapseOwned {
+		if owner := cleanerFactory.owners.ownerOf(obj); owner != "" {
+			log.Printf("Dropping %s '%v': managed by %s in the input", obj.Kind, obj.Metadata["name"], owner)
+			obj.dropped = "cleanupManifest:owned"
+			report.finish(obj, cleanerFactory.writer)
+			return
+		}
+	}
+
+	// Keep rules need the values as they were before the built-in cleaners ran
+	kept := captureKeptFields(obj, options.FieldRules)
+
 	cleaner := cleanerFactory.GetCleaner(obj.Kind)
 	// Cleaner factory now guarantees a non-nil cleaner (returns Generic if specific not found)
 	cleaner.Clean(obj, options)
 
+	// The replicas of autoscaled workloads belong to their autoscaler; applying them would fight it
+	if options.CollapseOwned && obj.Spec != nil && cleanerFactory.owners.isAutoscaled(obj) {
+		obj.Changes.remove(obj.Spec, "replicas", "cleanupManifest:owned")
+	}
+
+	retargetNamespaces(obj, options)
+	applyFieldRules(obj, options.FieldRules, kept)
+	report.finish(obj, cleanerFactory.writer)
+
 	// The removeEmptyFields logic is now integrated into the cleaners or called at the end.
 }
 
-// cleanupManifest processes the input YAML, cleans each object, and writes the cleaned YAML to the output.
+// builtinKinds are built-in kinds without a cleaner or a bundled classification. Their typed lists
+// (EndpointsList, ...) are unwrapped like the others.
+var builtinKinds = []string{
+	"Endpoints", "EndpointSlice", "Event", "LimitRange", "ResourceQuota", "Node", "StorageClass", "PriorityClass",
+	"CustomResourceDefinition", "APIService", "MutatingWebhookConfiguration", "ValidatingWebhookConfiguration", "Lease",
+}
+
+// isList reports whether obj is a List wrapper: an object with an items array whose kind is List
+// or the typed list of a known kind, such as DeploymentList or the list of a custom resource with
+// a loaded schema. Other kinds ending in List (e.g. AllowList) are objects of their own.
+func (f *ObjectCleanerFactory) isList(obj *KubernetesObject, options *CleanupOptions) bool {
+	if obj.Items == nil {
+		return false
+	}
+	if obj.Kind == "List" {
+		return true
+	}
+	kind, ok := strings.CutSuffix(obj.Kind, "List")
+	if !ok || kind == "" || kind == "Generic" {
+		return false
+	}
+	_, known := f.cleaners[kind]
+	return known || slices.Contains(builtinKinds, kind) || schemasFor(options).byKind[kind] != nil
+}
+
+// cleanupListObject cleans every item of a List object and returns the objects to encode.
+// With ExplodeLists, or when splitting the output into files or a chart, the cleaned items are returned as separate objects, otherwise the
+// cleaned List itself is returned.
+func cleanupListObject(list *KubernetesObject, options *CleanupOptions, cleanerFactory *ObjectCleanerFactory) []KubernetesObject {
+	// Typed lists (e.g. DeploymentList from the raw API) may omit apiVersion/kind on their items
+	itemKind := ""
+	if list.Kind != "List" {
+		itemKind = strings.TrimSuffix(list.Kind, "List")
+	}
+
+	cleanedItems := make([]KubernetesObject, 0, len(list.Items))
+	for i := range list.Items {
+		item := list.Items[i]
+		if item.Kind == "" {
+			item.Kind = itemKind
+		}
+		if item.APIVersion == "" && itemKind != "" {
+			item.APIVersion = list.APIVersion
+		}
+		if item.Kind == "" || item.APIVersion == "" {
+			log.Printf("Skipping item %d of %s: Missing Kind or APIVersion.", i+1, list.Kind)
+			continue
+		}
+
+		var itemName interface{} = "<unknown>"
+		if item.Metadata != nil {
+			if name, ok := item.Metadata["name"]; ok {
+				itemName = name
+			}
+		}
+		log.Printf("Processing %s item %d: %s/%s (%v)", list.Kind, i+1, item.APIVersion, item.Kind, itemName)
+
+		cleanupKubernetesObject(&item, options, cleanerFactory)
+		if item.dropped == "" {
+			cleanedItems = append(cleanedItems, item)
+		}
+	}
+
+	if options.ExplodeLists || options.Split != nil || options.Chart != nil {
+		return cleanedItems
+	}
+
+	// Re-wrap the cleaned items; the List metadata only carries runtime fields
+	list.Items = cleanedItems
+	if list.Metadata != nil {
+		delete(list.Metadata, "resourceVersion")
+		delete(list.Metadata, "selfLink")
+		delete(list.Metadata, "continue")
+		delete(list.Metadata, "remainingItemCount")
+		if len(list.Metadata) == 0 {
+			list.Metadata = nil
+		}
+	}
+	return []KubernetesObject{*list}
+}
+
+// decodedDocument is a document of the input with the node tree it was decoded from (nil for JSON).
+type decodedDocument struct {
+	obj    KubernetesObject
+	source *yamlv3.Node
+}
+
+// cleanupManifest processes the input YAML or JSON, cleans each object, and writes the cleaned objects to the output.
+// YAML documents are decoded as node trees so that comments, key order and scalar styles survive the cleanup.
 func cleanupManifest(input io.Reader, output io.Writer, options *CleanupOptions) error {
 	reader := bufio.NewReader(input)
-	decoder := yaml.NewDecoder(reader)
-	encoder := yaml.NewEncoder(output)
-	// encoder.SetIndent(2) // <-- REMOVED: SetIndent is not available in yaml.v2
-	defer encoder.Close()
-
-	documentCount := 0
-	cleanerFactory := NewObjectCleanerFactory()
+	writer := &documentWriter{w: output, explicitStart: hasExplicitStart(reader), keyOrder: options.KeyOrder, format: options.OutputFormat, split: options.Split, chart: options.Chart}
+	documents := newDocumentReader(reader)
+	if _, ok := documents.(*jsonDocumentReader); ok {
+		writer.keyOrder = keyOrderCanonical // JSON input has no key order to preserve
+	}
 
+	// Read every document first: children are collapsed into owners that may come after them
+	var decoded []decodedDocument
 	for {
-		var obj KubernetesObject
-		// Use Decode directly into the struct
-		err := decoder.Decode(&obj)
-
+		obj, source, err := documents.next()
 		if err == io.EOF {
-			if documentCount == 0 {
-				// Allow empty input without error, just produce no output
-				log.Println("Input contained no YAML documents.")
-				return nil // Changed from error to nil for empty input case
-			}
 			break // End of input stream
 		}
 		if err != nil {
-			// var genericDoc interface{} // <-- REMOVED: Variable declared but not used
-			// Attempt to provide more context on the decoding error.
-			// Reading the raw segment that failed might be complex with bufio.Reader.
-			// For now, just report the error.
-			return fmt.Errorf("error decoding YAML document %d: %w. Check YAML syntax near this document", documentCount+1, err)
+			return fmt.Errorf("error decoding %s document %d: %w. Check %s syntax near this document", documents.format(), len(decoded)+1, err, documents.format())
 		}
+		normalizeObject(&obj)
+		decoded = append(decoded, decodedDocument{obj: obj, source: source})
+	}
+	if len(decoded) == 0 {
+		// Allow empty input without error, just produce no output
+		log.Printf("Input contained no %s documents.", documents.format())
+		return nil // Changed from error to nil for empty input case
+	}
 
+	documentCount := 0
+	cleanerFactory := NewObjectCleanerFactory()
+	cleanerFactory.owners = options.Owners
+	cleanerFactory.writer = writer
+	if options.Reconstructed != nil {
+		cleanerFactory.reconstructed = options.Reconstructed
+	}
+	if cleanerFactory.owners == nil {
+		cleanerFactory.owners = newOwnerIndex(decoded)
+	}
+
+	for _, document := range decoded {
+		obj, source := document.obj, document.source
 		documentCount++
 
 		// Basic validation: Check if it looks like a K8s object
-		if obj.Kind == "" && obj.APIVersion == "" {
-			// It might be a comment block, an empty document (---), or non-K8s YAML.
-			// We choose to skip these silently for now.
-			log.Printf("Skipping document %d: Missing Kind and APIVersion.", documentCount)
-			continue
-		}
-		if obj.Kind == "" {
-			log.Printf("Skipping document %d: Missing Kind (APIVersion: %s).", documentCount, obj.APIVersion)
-			continue
+		var missing string
+		switch {
+		case obj.Kind == "" && obj.APIVersion == "":
+			// It might be a comment block, an empty document (---), or non-K8s YAML
+			missing = "Missing Kind and APIVersion"
+		case obj.Kind == "":
+			missing = fmt.Sprintf("Missing Kind (APIVersion: %s)", obj.APIVersion)
+		case obj.APIVersion == "":
+			missing = fmt.Sprintf("Missing APIVersion (Kind: %s)", obj.Kind)
 		}
-		if obj.APIVersion == "" {
-			log.Printf("Skipping document %d: Missing APIVersion (Kind: %s).", documentCount, obj.Kind)
+		if missing != "" {
+			if !options.KeepUnknown {
+				log.Printf("Skipping document %d: %s.", documentCount, missing)
+				continue
+			}
+			// A rewritten file must not lose the documents it does not clean
+			log.Printf("Keeping document %d unchanged: %s.", documentCount, missing)
+			if err := writer.writeUnchanged(&obj, source); err != nil {
+				return fmt.Errorf("error encoding document %d: %w", documentCount, err)
+			}
 			continue
 		}
 
@@ -1318,30 +1425,35 @@ func cleanupManifest(input io.Reader, output io.Writer, options *CleanupOptions)
 		}
 		log.Printf("Processing document %d: %s/%s (%v)", documentCount, obj.APIVersion, obj.Kind, objName)
 
-		cleanupKubernetesObject(&obj, options, cleanerFactory)
+		// Lists are unwrapped so every item goes through its kind-specific cleaner
+		if cleanerFactory.isList(&obj, options) {
+			for _, cleaned := range cleanupListObject(&obj, options, cleanerFactory) {
+				if err := writer.encodeObject(&cleaned, source); err != nil {
+					return fmt.Errorf("error encoding cleaned document %d (%s/%s %v): %w", documentCount, cleaned.APIVersion, cleaned.Kind, objName, err)
+				}
+			}
+			continue
+		}
 
-		// Check if the object became "empty" after cleaning (e.g., only apiVersion/kind left)
-		// This might happen if a runtime object was aggressively cleaned.
-		// We still encode it, as apiVersion/kind might be useful context.
-		// If obj.Metadata == nil && obj.Spec == nil && obj.Status == nil && obj.Data == nil && obj.StringData == nil {
-		//  log.Printf("Note: Document %d (%s/%s %v) is effectively empty after cleaning.", documentCount, obj.APIVersion, obj.Kind, objName)
-		// }
+		cleanupKubernetesObject(&obj, options, cleanerFactory)
+		if obj.dropped != "" {
+			continue
+		}
 
 		// Encode the cleaned object
-		err = encoder.Encode(obj)
-		if err != nil {
+		if err := writer.encodeObject(&obj, source); err != nil {
 			// This error is less likely but possible (e.g., IO error on output)
-			return fmt.Errorf("error encoding cleaned YAML document %d (%s/%s %v): %w", documentCount, obj.APIVersion, obj.Kind, objName, err)
+			return fmt.Errorf("error encoding cleaned document %d (%s/%s %v): %w", documentCount, obj.APIVersion, obj.Kind, objName, err)
 		}
 	}
 
-	log.Printf("Successfully processed %d YAML documents.", documentCount)
+	log.Printf("Successfully processed %d %s documents.", documentCount, documents.format())
 	return nil
 }
 
-func main() {
-	// Default options (can be overridden by flags later)
-	options := &CleanupOptions{
+// defaultCleanupOptions returns the options klean uses when nothing else is configured.
+func defaultCleanupOptions() *CleanupOptions {
+	return &CleanupOptions{
 		RemoveManagedFields:   true,       // Remove kubectl internal annotations, etc.
 		RemoveStatus:          true,       // Remove runtime status block
 		RemoveNamespace:       true,       // Make objects namespace-agnostic
@@ -1350,61 +1462,14 @@ func main() {
 		RemoveAnnotations:     []string{}, // No specific annotations to remove by default
 		RemoveEmpty:           true,       // Clean up empty maps/slices at the end
 		CleanupFinalizers:     true,       // Remove finalizers
-		RevertToDeployment:    true,       // Try to revert ownerless Pods to Deployments
+		RevertToDeployment:    true,       // Try to revert Pods to the controllers that created them
 		PreserveResourceState: false,      // Default: Don't preserve specific state, clean generally
 		ResourceStateMode:     "Desired",  // Default mode if PreserveResourceState is true
+		ExplodeLists:          false,      // Default: Re-emit List documents as a cleaned List
+		CollapseOwned:         true,       // Re-applying the output must not create orphaned duplicates
+		RemoveSuspend:         false,      // A suspended Job stays suspended
+		VolumeBinding:         volumeBindingUnbind,
+		KeyOrder:              keyOrderPreserve,
+		OutputFormat:          outputFormatYAML,
 	}
-
-	// Setup logging
-	log.SetOutput(os.Stderr) // Log to stderr
-	log.SetPrefix("[Kleanup] ")
-	// log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile) // Keep it simple for CLI tool
-	log.SetFlags(log.Ltime)
-
-	// TODO: Add flag parsing here to override default options
-	// Example using 'flag' package:
-	// flag.BoolVar(&options.RemoveManagedFields, "remove-managed-fields", true, "Remove metadata.managedFields")
-	// flag.BoolVar(&options.RemoveStatus, "remove-status", true, "Remove status block")
-	// flag.BoolVar(&options.RemoveNamespace, "remove-namespace", true, "Remove metadata.namespace")
-	// flag.BoolVar(&options.RemoveEmpty, "remove-empty", true, "Remove empty fields/maps/slices after cleaning")
-	// flag.BoolVar(&options.CleanupFinalizers, "cleanup-finalizers", true, "Remove metadata.finalizers")
-	// flag.BoolVar(&options.RevertToDeployment, "revert-pod-to-deployment", true, "Attempt to revert standalone Pods to Deployments")
-	// flag.BoolVar(&options.PreserveResourceState, "preserve-state", false, "Preserve specific desired or runtime state fields")
-	// flag.StringVar(&options.ResourceStateMode, "state-mode", "Desired", "Mode for state preservation ('Desired' or 'Runtime')")
-	// // Add flags for RemoveLabels and RemoveAnnotations (e.g., using a custom flag type for slices)
-	// flag.Parse()
-
-	// --- Input/Output Handling ---
-	var input io.Reader = os.Stdin
-	var output io.Writer = os.Stdout
-	var err error
-
-	// Basic argument handling (replace with flag package later)
-	// Example: kleanup input.yaml > output.yaml
-	// Example: cat input.yaml | kleanup > output.yaml
-	// if len(os.Args) > 1 {
-	// 	inputFile := os.Args[1]
-	// 	if inputFile != "-" { // Allow "-" for stdin explicitly
-	// 		file, err := os.Open(inputFile)
-	// 		if err != nil {
-	// 			fmt.Fprintf(os.Stderr, "Error opening input file '%s': %v\n", inputFile, err)
-	// 			os.Exit(1)
-	// 		}
-	// 		defer file.Close()
-	// 		input = file
-	// 		log.Printf("Reading from file: %s", inputFile)
-	// 	} else {
-	//      log.Println("Reading from stdin...")
-	//  }
-	// } else {
-	// 	log.Println("Reading from stdin...")
-	// }
-	// Add similar logic for output file if needed
-
-	log.Println("Starting cleanup...")
-	if err = cleanupManifest(input, output, options); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
-	log.Println("Cleanup finished successfully.")
 }
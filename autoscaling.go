@@ -0,0 +1,65 @@
+package main
+
+import "log"
+
+// hpaRuntimeAnnotations are the annotations autoscaling/v1 uses to expose the status of the newer
+// API versions. The autoscaling.alpha.kubernetes.io/metrics and behavior annotations hold spec
+// fields and are kept.
+var hpaRuntimeAnnotations = []string{
+	"autoscaling.alpha.kubernetes.io/conditions",
+	"autoscaling.alpha.kubernetes.io/current-metrics",
+}
+
+// hpaStatusRuntimeFields and pdbStatusRuntimeFields are the status fields of autoscalers and
+// disruption budgets, all of which are computed from the Pods of the source cluster.
+var (
+	hpaStatusRuntimeFields = []string{"currentMetrics", "currentReplicas", "desiredReplicas", "lastScaleTime", "conditions", "observedGeneration"}
+	pdbStatusRuntimeFields = []string{"currentHealthy", "desiredHealthy", "disruptionsAllowed", "expectedPods", "disruptedPods", "conditions", "observedGeneration"}
+)
+
+// deprecatedHPAVersions maps deprecated HorizontalPodAutoscaler API versions to the version they
+// are converted to. Only versions with the same schema are converted.
+var deprecatedHPAVersions = map[string]string{
+	"autoscaling/v2beta2": "autoscaling/v2",
+}
+
+// HorizontalPodAutoscalerCleaner cleans HorizontalPodAutoscaler-specific fields.
+type HorizontalPodAutoscalerCleaner struct {
+	genericCleaner ObjectCleaner
+}
+
+func (c *HorizontalPodAutoscalerCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
+	c.genericCleaner.Clean(obj, options) // Removes minReplicas: 1
+
+	if version, ok := deprecatedHPAVersions[obj.APIVersion]; ok {
+		log.Printf("Converting HorizontalPodAutoscaler '%v' from %s to %s", obj.Metadata["name"], obj.APIVersion, version)
+		obj.Changes.recordPath("apiVersion", obj.APIVersion, "HorizontalPodAutoscalerCleaner:convert")
+		obj.APIVersion = version
+	}
+	if annotations, ok := obj.Metadata["annotations"].(map[string]interface{}); ok {
+		for _, key := range hpaRuntimeAnnotations {
+			obj.Changes.remove(annotations, key, "HorizontalPodAutoscalerCleaner:runtime")
+		}
+		if len(annotations) == 0 {
+			obj.Changes.remove(obj.Metadata, "annotations", "HorizontalPodAutoscalerCleaner:empty")
+		}
+	}
+	removeRuntimeStatus(obj, options, hpaStatusRuntimeFields, "HorizontalPodAutoscalerCleaner")
+	if options.RemoveEmpty {
+		cleanupEmptyTopLevelFields(obj)
+	}
+}
+
+// PodDisruptionBudgetCleaner cleans PodDisruptionBudget-specific fields.
+type PodDisruptionBudgetCleaner struct {
+	genericCleaner ObjectCleaner
+}
+
+func (c *PodDisruptionBudgetCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
+	c.genericCleaner.Clean(obj, options)
+
+	removeRuntimeStatus(obj, options, pdbStatusRuntimeFields, "PodDisruptionBudgetCleaner")
+	if options.RemoveEmpty {
+		cleanupEmptyTopLevelFields(obj)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCleanupManifestAutoscaledReplicas(t *testing.T) {
+	input := `apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata: {name: web, namespace: shop}
+spec:
+  maxReplicas: 10
+  scaleTargetRef: {apiVersion: apps/v1, kind: Deployment, name: web}
+status:
+  currentReplicas: 3
+  desiredReplicas: 4
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web, namespace: shop}
+spec:
+  replicas: 3
+  selector: {matchLabels: {app: web}}
+  template:
+    metadata: {labels: {app: web}}
+    spec:
+      containers: [{name: web, image: nginx}]
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web, namespace: staging}
+spec:
+  replicas: 2
+  selector: {matchLabels: {app: web}}
+  template:
+    metadata: {labels: {app: web}}
+    spec:
+      containers: [{name: web, image: nginx}]
+`
+	tests := []struct {
+		name             string
+		collapseOwned    bool
+		expectedContains []string
+		expectedMissing  []string
+	}{
+		{
+			name:             "replicas of the scale target are removed",
+			collapseOwned:    true,
+			expectedContains: []string{"replicas: 2"},
+			expectedMissing:  []string{"replicas: 3", "currentReplicas", "desiredReplicas", "status"},
+		},
+		{
+			name:             "disabled",
+			collapseOwned:    false,
+			expectedContains: []string{"replicas: 3", "replicas: 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := defaultCleanupOptions()
+			options.RemoveStatus = false // The autoscaler status is runtime state either way
+			options.CollapseOwned = tt.collapseOwned
+			var output bytes.Buffer
+			if err := cleanupManifest(strings.NewReader(input), &output, options); err != nil {
+				t.Fatalf("cleanupManifest returned error: %v", err)
+			}
+			for _, expected := range tt.expectedContains {
+				if !strings.Contains(output.String(), expected) {
+					t.Errorf("Expected output to contain %q, got:\n%s", expected, output.String())
+				}
+			}
+			for _, missing := range tt.expectedMissing {
+				if strings.Contains(output.String(), missing) {
+					t.Errorf("Expected output not to contain %q, got:\n%s", missing, output.String())
+				}
+			}
+		})
+	}
+}
+
+func TestHorizontalPodAutoscalerConversionIsRecorded(t *testing.T) {
+	obj := &KubernetesObject{
+		APIVersion: "autoscaling/v2beta2",
+		Kind:       "HorizontalPodAutoscaler",
+		Metadata:   map[string]interface{}{"name": "web"},
+		Spec:       map[string]interface{}{"maxReplicas": 5},
+	}
+	changes := cleanObject(obj, defaultCleanupOptions())
+
+	if obj.APIVersion != "autoscaling/v2" {
+		t.Errorf("Expected apiVersion autoscaling/v2, got %s", obj.APIVersion)
+	}
+	expected := Change{Path: "apiVersion", OldValue: "autoscaling/v2beta2", Reason: "HorizontalPodAutoscalerCleaner:convert"}
+	found := false
+	for _, change := range changes {
+		found = found || change == expected
+	}
+	if !found {
+		t.Errorf("Expected the conversion to be recorded.\nExpected: %v\nActual: %v", expected, changes)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Change is a field removed, or rewritten, while cleaning an object.
+type Change struct {
+	// Path of the removed field in field rule syntax, e.g.
+	// spec.template.spec.containers[?(@.name=="app")].imagePullPolicy. Empty when the whole object
+	// was dropped from the output.
+	Path     string      `json:"path"`
+	OldValue interface{} `json:"oldValue"`
+	// Reason names the cleaner or function and why the field was removed, e.g. cleanContainerSpec:default.
+	// Categories: runtime (cluster-managed state), default (API server default), option (requested by
+	// an option), state (state preservation), empty (left empty by cleaning), revert (Pod reverted
+	// to its controller), duplicate (dropped as a duplicate), owned (managed by a controller that
+	// is part of the input) and convert (rewritten to a newer API version; OldValue is the value
+	// before the rewrite). Field rules use rule:<path>.
+	Reason string `json:"reason"`
+}
+
+// ChangeRecorder records the changes made to the object it is attached to.
+// A nil recorder records nothing, so cleaners can use it unconditionally.
+type ChangeRecorder struct {
+	obj     *KubernetesObject
+	changes []Change
+	paths   map[uintptr]indexedMap // Paths of the maps in obj, rebuilt when a map is not found
+}
+
+// indexedMap keeps a map referenced by the path index alive, so its address cannot be reused.
+type indexedMap struct {
+	m    map[string]interface{}
+	path string
+}
+
+// recordChanges attaches a new ChangeRecorder to obj, replacing any previous one, and returns it.
+// Cleaning the object then records every removed field.
+func (obj *KubernetesObject) recordChanges() *ChangeRecorder {
+	obj.Changes = &ChangeRecorder{obj: obj}
+	return obj.Changes
+}
+
+// Changes returns the recorded changes, in the order they were made.
+func (r *ChangeRecorder) Changes() []Change {
+	if r == nil {
+		return nil
+	}
+	return append([]Change(nil), r.changes...)
+}
+
+// cleanObject cleans obj with options and returns every change cleaning made.
+func cleanObject(obj *KubernetesObject, options *CleanupOptions) []Change {
+	recorder := obj.recordChanges()
+	cleanupKubernetesObject(obj, options, NewObjectCleanerFactory())
+	return recorder.Changes()
+}
+
+// recordPath records the removal of the field at path.
+func (r *ChangeRecorder) recordPath(path string, value interface{}, reason string) {
+	if r == nil {
+		return
+	}
+	r.changes = append(r.changes, Change{Path: path, OldValue: deepCopyValue(value), Reason: reason})
+}
+
+// remove deletes key from m, a map of the recorded object, and records the removal.
+func (r *ChangeRecorder) remove(m map[string]interface{}, key, reason string) {
+	value, exists := m[key]
+	if !exists {
+		return
+	}
+	if r != nil {
+		r.recordPath(appendKeyPath(r.pathOf(m), key), value, reason)
+	}
+	delete(m, key)
+}
+
+// recordItem records the removal of items[index] from the list stored at m[key].
+func (r *ChangeRecorder) recordItem(m map[string]interface{}, key string, items []interface{}, index int, reason string) {
+	if r == nil {
+		return
+	}
+	r.recordPath(listItemPath(appendKeyPath(r.pathOf(m), key), items, index), items[index], reason)
+}
+
+// mark returns a position in the recorded changes for rollback.
+func (r *ChangeRecorder) mark() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.changes)
+}
+
+// rollback discards the changes recorded since mark, e.g. when a parent removal supersedes them.
+func (r *ChangeRecorder) rollback(mark int) {
+	if r != nil && mark < len(r.changes) {
+		r.changes = r.changes[:mark]
+	}
+}
+
+// forget discards the changes recorded at or below path, e.g. for a field restored by a keep rule.
+func (r *ChangeRecorder) forget(path string) {
+	if r == nil {
+		return
+	}
+	kept := r.changes[:0]
+	for _, change := range r.changes {
+		if !isPathAtOrBelow(change.Path, path) {
+			kept = append(kept, change)
+		}
+	}
+	r.changes = kept
+}
+
+// isPathAtOrBelow reports whether path equals ancestor or addresses a field below it.
+func isPathAtOrBelow(path, ancestor string) bool {
+	if !strings.HasPrefix(path, ancestor) {
+		return false
+	}
+	rest := path[len(ancestor):]
+	return rest == "" || rest[0] == '.' || rest[0] == '['
+}
+
+// invalidate drops the path index, for cleaners that move maps to a different path.
+func (r *ChangeRecorder) invalidate() {
+	if r != nil {
+		r.paths = nil
+	}
+}
+
+// pathOf returns the path of m in the recorded object, or "<unknown>" for maps outside of it.
+func (r *ChangeRecorder) pathOf(m map[string]interface{}) string {
+	if r == nil {
+		return ""
+	}
+	key := reflect.ValueOf(m).Pointer()
+	if entry, ok := r.paths[key]; ok {
+		return entry.path
+	}
+	// Cleaners replace maps while cleaning; index the current tree again
+	r.paths = map[uintptr]indexedMap{}
+	r.index(objectToMap(r.obj), "")
+	if entry, ok := r.paths[key]; ok {
+		return entry.path
+	}
+	return "<unknown>"
+}
+
+// index adds every map below data to the path index.
+func (r *ChangeRecorder) index(data interface{}, path string) {
+	switch typed := data.(type) {
+	case map[string]interface{}:
+		if path != "" {
+			r.paths[reflect.ValueOf(typed).Pointer()] = indexedMap{m: typed, path: path}
+		}
+		for key, value := range typed {
+			r.index(value, appendKeyPath(path, key))
+		}
+	case []interface{}:
+		for i, item := range typed {
+			r.index(item, listItemPath(path, typed, i))
+		}
+	}
+}
+
+// listItemPath returns the path of items[index] below path. Elements of named lists are addressed
+// by a name filter, so their paths do not depend on the position of other elements.
+func listItemPath(path string, items []interface{}, index int) string {
+	if namedList(items) {
+		name, _ := listElementName(items[index])
+		return fmt.Sprintf("%s[?(@.name==%q)]", path, name)
+	}
+	return fmt.Sprintf("%s[%d]", path, index)
+}
+
+// auditEntry is one line of the audit log: a change and the object it was made to.
+type auditEntry struct {
+	Object string `json:"object"`
+	Change
+}
+
+// writeAuditLog writes every change in report as a JSON line.
+func writeAuditLog(w io.Writer, report *CleanupReport) error {
+	encoder := json.NewEncoder(w)
+	for _, object := range report.Objects {
+		for _, change := range object.Removed {
+			if err := encoder.Encode(auditEntry{Object: object.key(), Change: change}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestCleanObjectRecordsReasons(t *testing.T) {
+	input := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+  uid: 0b7e2f4c
+  annotations:
+    deployment.kubernetes.io/revision: "3"
+spec:
+  replicas: 2
+  revisionHistoryLimit: 10
+  template:
+    spec:
+      dnsPolicy: ClusterFirst
+      containers:
+      - name: app
+        image: nginx:1.25
+        imagePullPolicy: IfNotPresent
+        stdin: true
+status:
+  replicas: 2
+`
+	var obj KubernetesObject
+	if err := yaml.Unmarshal([]byte(input), &obj); err != nil {
+		t.Fatalf("failed to parse input: %v", err)
+	}
+	normalizeObject(&obj)
+
+	changes := cleanObject(&obj, defaultCleanupOptions())
+	reasons := map[string]Change{}
+	for _, change := range changes {
+		reasons[change.Path] = change
+	}
+
+	expected := []Change{
+		{Path: "metadata.uid", OldValue: "0b7e2f4c", Reason: "GenericMetadataCleaner:runtime"},
+		{Path: "metadata.namespace", OldValue: "shop", Reason: "GenericMetadataCleaner:option"},
+		{Path: "metadata.annotations['deployment.kubernetes.io/revision']", OldValue: "3", Reason: "cleanAnnotations:runtime"},
+		{Path: "spec.revisionHistoryLimit", OldValue: 10, Reason: "GenericObjectCleaner:default"},
+		{Path: `spec.template.spec.containers[?(@.name=="app")].imagePullPolicy`, OldValue: "IfNotPresent", Reason: "cleanContainerSpec:default"},
+		{Path: "spec.template.spec.dnsPolicy", OldValue: "ClusterFirst", Reason: "cleanPodSpec:default"},
+		{Path: "status", OldValue: map[string]interface{}{"replicas": 2}, Reason: "GenericObjectCleaner:runtime"},
+	}
+	for _, e := range expected {
+		actual, ok := reasons[e.Path]
+		if !ok {
+			t.Errorf("Expected a change at %s, got %v", e.Path, changes)
+			continue
+		}
+		if actual.Reason != e.Reason || !valuesEqual(e.OldValue, actual.OldValue) {
+			t.Errorf("Unexpected change at %s.\nExpected: %v\nActual: %v", e.Path, e, actual)
+		}
+	}
+	if _, ok := reasons[`spec.template.spec.containers[?(@.name=="app")].stdin`]; ok {
+		t.Errorf("Expected stdin: true to be kept, got %v", changes)
+	}
+}
+
+// TestChangesCoverRemovedFields checks that every field missing from the cleaned golden outputs
+// was recorded, at its own path or at one of its parents or children.
+func TestChangesCoverRemovedFields(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "golden", "*.yaml"))
+	if err != nil {
+		t.Fatalf("failed to list golden inputs: %v", err)
+	}
+
+	for _, inputPath := range inputs {
+		if strings.HasSuffix(inputPath, ".golden.yaml") {
+			continue
+		}
+		t.Run(filepath.Base(inputPath), func(t *testing.T) {
+			input, err := os.ReadFile(inputPath)
+			if err != nil {
+				t.Fatalf("failed to read input: %v", err)
+			}
+			options := defaultCleanupOptions()
+			options.Report = &CleanupReport{}
+			if err := cleanupManifest(bytes.NewReader(input), &bytes.Buffer{}, options); err != nil {
+				t.Fatalf("cleanupManifest returned error: %v", err)
+			}
+
+			for _, object := range options.Report.Objects {
+				for _, change := range object.Removed {
+					if strings.Contains(change.Path, "<unknown>") || change.Reason == "" {
+						t.Errorf("%s: incomplete change %v", object.key(), change)
+					}
+				}
+				for _, removed := range removedFields(object.original, object.cleaned, "") {
+					covered := false
+					for _, change := range object.Removed {
+						// An empty path covers the whole object, dropped from the output
+						if change.Path == "" || isPathAtOrBelow(removed.Path, change.Path) || isPathAtOrBelow(change.Path, removed.Path) {
+							covered = true
+							break
+						}
+					}
+					if !covered {
+						t.Errorf("%s: removal of %s was not recorded", object.key(), removed.Path)
+					}
+				}
+			}
+		})
+	}
+}
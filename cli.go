@@ -0,0 +1,648 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Build information, set by goreleaser via -ldflags "-X main.version=...".
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
+// Exit codes returned by run.
+const (
+	exitOK    = 0 // Cleanup finished successfully
+	exitError = 1 // Input could not be read, decoded or written
+	exitUsage = 2 // Invalid flags or arguments
+)
+
+// stringSliceFlag is a repeatable flag that also accepts comma-separated values.
+type stringSliceFlag struct {
+	values *[]string
+}
+
+func (f stringSliceFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	return strings.Join(*f.values, ",")
+}
+
+func (f stringSliceFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*f.values = append(*f.values, v)
+		}
+	}
+	return nil
+}
+
+// stringMapFlag is a repeatable flag of comma-separated key=value pairs.
+type stringMapFlag struct {
+	values *map[string]string
+}
+
+func (f stringMapFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(*f.values))
+	for _, key := range slices.Sorted(maps.Keys(*f.values)) {
+		pairs = append(pairs, key+"="+(*f.values)[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f stringMapFlag) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || key == "" || val == "" {
+			return fmt.Errorf("invalid pair %q: must be key=value", pair)
+		}
+		if *f.values == nil {
+			*f.values = map[string]string{}
+		}
+		(*f.values)[key] = val
+	}
+	return nil
+}
+
+// cliOptions holds the settings that only concern the command line, not the cleanup itself.
+type cliOptions struct {
+	configPath  string
+	schemaFiles []string
+	outputPath  string
+	quiet       bool
+	verbose     bool
+	showVersion bool
+	diff        bool   // Print what cleaning removed instead of the cleaned manifests
+	diffFormat  string // diffFormatUnified or diffFormatStructured
+	color       string // "auto", "always" or "never"
+	report      string // "json" prints a machine-readable report instead of the cleaned manifests
+	auditLog    string // File receiving one JSON line per removed field
+	recursive   bool   // Walk directory arguments for manifests
+	inPlace     bool   // Rewrite every input file with its cleaned content
+	backup      bool   // Keep the original content of rewritten files in <file>.bak
+	outputDir   string // Write every cleaned file to the same relative path below this directory
+	splitDir    string // Write every cleaned object to its own file below this directory
+	splitName   string // Template naming the files written to splitDir
+	kustomize   bool   // Write a kustomization.yaml listing the files written to splitDir
+	kustLabels  string // Kustomization field that labels shared by every object are moved to
+	targetNS    string // Move every object, and the references to its namespace, to this namespace
+	helmify     bool   // "klean helmify": write the cleaned objects as a Helm chart
+	chartDir    string // Directory the chart is written to
+	chartName   string // Name of the chart; defaults to the name of chartDir
+}
+
+// perFile reports whether files are processed one by one: failures are collected into a summary
+// instead of aborting the run.
+func (cli *cliOptions) perFile() bool {
+	return cli.recursive || cli.inPlace || cli.outputDir != ""
+}
+
+// newFlagSet binds every CleanupOptions field and the CLI settings to a flag set.
+// The current values of options are used as flag defaults.
+func newFlagSet(options *CleanupOptions, cli *cliOptions, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("klean", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	fs.BoolVar(&options.RemoveManagedFields, "remove-managed-fields", options.RemoveManagedFields, "Remove metadata.managedFields")
+	fs.BoolVar(&options.RemoveStatus, "remove-status", options.RemoveStatus, "Remove status block")
+	fs.BoolVar(&options.RemoveNamespace, "remove-namespace", options.RemoveNamespace, "Remove metadata.namespace")
+	fs.BoolVar(&options.RemoveClusterName, "remove-cluster-name", options.RemoveClusterName, "Remove cluster name (not implemented yet)")
+	fs.Var(stringSliceFlag{&options.RemoveLabels}, "remove-label", "Label key to remove (repeatable, comma-separated)")
+	fs.Var(stringSliceFlag{&options.RemoveAnnotations}, "remove-annotation", "Annotation key to remove (repeatable, comma-separated)")
+	fs.BoolVar(&options.RemoveEmpty, "remove-empty", options.RemoveEmpty, "Remove empty fields/maps/slices after cleaning")
+	fs.BoolVar(&options.CleanupFinalizers, "cleanup-finalizers", options.CleanupFinalizers, "Remove metadata.finalizers")
+	fs.BoolVar(&options.RevertToDeployment, "revert-pod-to-deployment", options.RevertToDeployment, "Attempt to revert Pods to the controllers that created them (Deployment, StatefulSet, DaemonSet, ReplicaSet, Job), one per controller")
+	fs.BoolVar(&options.PreserveResourceState, "preserve-state", options.PreserveResourceState, "Preserve specific desired or runtime state fields")
+	fs.StringVar(&options.ResourceStateMode, "state-mode", options.ResourceStateMode, "Mode for state preservation ('Desired' or 'Runtime')")
+	fs.BoolVar(&options.ExplodeLists, "explode-lists", options.ExplodeLists, "Emit List items as separate YAML documents")
+	fs.BoolVar(&options.CollapseOwned, "collapse-owned", options.CollapseOwned, "Drop objects recreated by a controller that is part of the input (Pods, ReplicaSets, Jobs of CronJobs, Endpoints, EndpointSlices, ControllerRevisions) and the replicas of workloads scaled by a HorizontalPodAutoscaler in the input")
+	fs.BoolVar(&options.RemoveSuspend, "remove-suspend", options.RemoveSuspend, "Remove spec.suspend from Jobs and CronJobs, so suspended ones run once applied")
+	fs.StringVar(&options.VolumeBinding, "volume-binding", options.VolumeBinding, "PersistentVolumeClaim/PersistentVolume bindings: 'unbind' (drop volumeName and claimRef, for dynamic provisioning) or 'preserve' (keep static bindings)")
+	fs.BoolVar(&options.SkipSystemRBAC, "skip-system-rbac", options.SkipSystemRBAC, "Drop system:* Roles, ClusterRoles, RoleBindings and ClusterRoleBindings managed by the cluster")
+	fs.BoolVar(&options.KeepDefaults, "keep-defaults", options.KeepDefaults, "Keep fields that hold their API server default value")
+	fs.StringVar(&options.OutputFormat, "output-format", options.OutputFormat, "Output format: 'yaml', 'json' or 'jsonl' (one object per line); input format is detected")
+	fs.Var(stringMapFlag{&options.NamespaceMap}, "namespace-map", "Rewrite namespaces and every reference to them instead of removing them, as comma-separated `source=target` pairs (repeatable)")
+	fs.StringVar(&options.KeyOrder, "key-order", options.KeyOrder, "Key order of the output: 'preserve' (input order) or 'canonical' (apiVersion, kind, metadata, spec, ...); JSON input is always written in canonical order")
+
+	fs.Var(stringSliceFlag{&cli.schemaFiles}, "crd-schema", "CRD manifest or OpenAPI v3 `file` used to classify desired/runtime fields (repeatable)")
+	fs.StringVar(&cli.configPath, "config", cli.configPath, "Load the cleanup profile from `file` instead of searching for .kleanup.yaml")
+	fs.StringVar(&cli.outputPath, "o", cli.outputPath, "Write output to `file` instead of stdout")
+	fs.StringVar(&cli.outputPath, "output", cli.outputPath, "Write output to `file` instead of stdout")
+	fs.BoolVar(&cli.quiet, "q", cli.quiet, "Only print errors")
+	fs.BoolVar(&cli.quiet, "quiet", cli.quiet, "Only print errors")
+	fs.BoolVar(&cli.verbose, "v", cli.verbose, "Print detailed progress information")
+	fs.BoolVar(&cli.verbose, "verbose", cli.verbose, "Print detailed progress information")
+	fs.BoolVar(&cli.diff, "diff", cli.diff, "Dry run: print a diff per document between input and cleaned output")
+	fs.StringVar(&cli.diffFormat, "diff-format", diffFormatUnified, "Diff format: 'unified' or 'structured' (removed paths with the reason they were removed)")
+	fs.StringVar(&cli.color, "color", "auto", "Colorize diffs: 'auto' (when writing to a terminal), 'always' or 'never'")
+	fs.StringVar(&cli.report, "report", cli.report, "Dry run: print a report of every removed path in the given `format` ('json')")
+	fs.StringVar(&cli.auditLog, "audit-log", cli.auditLog, "Append one JSON line per removed field (object, path, old value, reason) to `file`")
+	fs.BoolVar(&cli.recursive, "R", cli.recursive, "Clean the .yaml, .yml and .json files below directory arguments")
+	fs.BoolVar(&cli.recursive, "recursive", cli.recursive, "Clean the .yaml, .yml and .json files below directory arguments")
+	fs.BoolVar(&cli.inPlace, "in-place", cli.inPlace, "Rewrite every input file with its cleaned content")
+	fs.BoolVar(&cli.backup, "backup", cli.backup, "With --in-place, keep the original content of rewritten files in <file>.bak")
+	fs.StringVar(&cli.outputDir, "output-dir", cli.outputDir, "Write every cleaned file to the same relative path below `dir`")
+	fs.StringVar(&cli.splitDir, "split-dir", cli.splitDir, "Write every cleaned object to its own file below `dir`")
+	fs.StringVar(&cli.splitName, "split-template", defaultSplitTemplate, "File name `template` for --split-dir; fields: .Namespace, .Kind, .Name, .APIVersion, .Group, .Version; functions: lower, upper")
+	fs.BoolVar(&cli.kustomize, "kustomize", cli.kustomize, "With --split-dir, also write a kustomization.yaml listing the files; a namespace removed from every object becomes its namespace")
+	fs.StringVar(&cli.kustLabels, "kustomize-labels", cli.kustLabels, "With --kustomize, move labels shared by every object to this kustomization `field` ('labels' or 'commonLabels')")
+	fs.StringVar(&cli.targetNS, "target-namespace", cli.targetNS, "Move every object to `namespace`, rewriting references to the namespaces of the input (like --namespace-map for each of them)")
+	fs.StringVar(&cli.chartDir, "chart-dir", cli.chartDir, "klean helmify: write the chart to `dir`")
+	fs.StringVar(&cli.chartName, "chart-name", cli.chartName, "klean helmify: chart `name` (default: the name of --chart-dir); object names starting with it are templated with .Release.Name")
+	fs.BoolVar(&cli.showVersion, "version", cli.showVersion, "Print version information and exit")
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: klean [flags] [file ...]\n")
+		fmt.Fprintf(fs.Output(), "       klean [flags] helmify --chart-dir dir [flags] [file ...]\n\n")
+		fmt.Fprintf(fs.Output(), "Cleans Kubernetes manifests read from the given files, or stdin if none (or '-') is given.\n")
+		fmt.Fprintf(fs.Output(), "helmify writes the cleaned manifests as a Helm chart with parameterised images, replicas, resources, ports and data.\n")
+		fmt.Fprintf(fs.Output(), "Settings are read from .kleanup.yaml (searched from the working directory upwards); flags take precedence.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// validateOptions checks option values that the flag package cannot validate on its own.
+func validateOptions(options *CleanupOptions) error {
+	if options.ResourceStateMode != "Desired" && options.ResourceStateMode != "Runtime" {
+		return fmt.Errorf("invalid state mode %q: must be 'Desired' or 'Runtime'", options.ResourceStateMode)
+	}
+	if options.OutputFormat != outputFormatYAML && options.OutputFormat != outputFormatJSON && options.OutputFormat != outputFormatJSONL {
+		return fmt.Errorf("invalid output format %q: must be '%s', '%s' or '%s'", options.OutputFormat, outputFormatYAML, outputFormatJSON, outputFormatJSONL)
+	}
+	if options.KeyOrder != keyOrderCanonical && options.KeyOrder != keyOrderPreserve {
+		return fmt.Errorf("invalid key order %q: must be '%s' or '%s'", options.KeyOrder, keyOrderCanonical, keyOrderPreserve)
+	}
+	if options.VolumeBinding != volumeBindingUnbind && options.VolumeBinding != volumeBindingPreserve {
+		return fmt.Errorf("invalid volume binding %q: must be '%s' or '%s'", options.VolumeBinding, volumeBindingUnbind, volumeBindingPreserve)
+	}
+	for _, source := range slices.Sorted(maps.Keys(options.NamespaceMap)) {
+		if err := validateNamespaceName(source); err != nil {
+			return fmt.Errorf("--namespace-map: %w", err)
+		}
+		if err := validateNamespaceName(options.NamespaceMap[source]); err != nil {
+			return fmt.Errorf("--namespace-map: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateCLIOptions checks the command line settings for invalid values and conflicts.
+func validateCLIOptions(cli *cliOptions) error {
+	if cli.quiet && cli.verbose {
+		return errors.New("--quiet and --verbose are mutually exclusive")
+	}
+	if cli.diffFormat != diffFormatUnified && cli.diffFormat != diffFormatStructured {
+		return fmt.Errorf("invalid diff format %q: must be '%s' or '%s'", cli.diffFormat, diffFormatUnified, diffFormatStructured)
+	}
+	if cli.color != "auto" && cli.color != "always" && cli.color != "never" {
+		return fmt.Errorf("invalid color mode %q: must be 'auto', 'always' or 'never'", cli.color)
+	}
+	if cli.report != "" && cli.report != "json" {
+		return fmt.Errorf("invalid report format %q: must be 'json'", cli.report)
+	}
+	if cli.diff && cli.report != "" {
+		return errors.New("--diff and --report are mutually exclusive")
+	}
+	if cli.inPlace && cli.outputDir != "" {
+		return errors.New("--in-place and --output-dir are mutually exclusive")
+	}
+	if (cli.inPlace || cli.outputDir != "") && cli.outputPath != "" {
+		return errors.New("--output cannot be combined with --in-place or --output-dir")
+	}
+	if (cli.inPlace || cli.outputDir != "") && (cli.diff || cli.report != "") {
+		return errors.New("--diff and --report are dry runs and cannot be combined with --in-place or --output-dir")
+	}
+	if cli.splitDir != "" && (cli.inPlace || cli.outputDir != "" || cli.outputPath != "" || cli.diff || cli.report != "") {
+		return errors.New("--split-dir cannot be combined with --in-place, --output-dir, --output, --diff or --report")
+	}
+	if cli.kustomize && cli.splitDir == "" {
+		return errors.New("--kustomize requires --split-dir")
+	}
+	if cli.kustLabels != "" && !cli.kustomize {
+		return errors.New("--kustomize-labels requires --kustomize")
+	}
+	if cli.kustLabels != "" && cli.kustLabels != kustomizeLabels && cli.kustLabels != kustomizeCommonLabels {
+		return fmt.Errorf("invalid kustomize labels field %q: must be '%s' or '%s'", cli.kustLabels, kustomizeLabels, kustomizeCommonLabels)
+	}
+	if !cli.helmify && (cli.chartDir != "" || cli.chartName != "") {
+		return errors.New("--chart-dir and --chart-name are only used by 'klean helmify'")
+	}
+	if cli.helmify && cli.chartDir == "" {
+		return errors.New("klean helmify requires --chart-dir")
+	}
+	if cli.helmify && (cli.splitDir != "" || cli.inPlace || cli.outputDir != "" || cli.outputPath != "" || cli.diff || cli.report != "") {
+		return errors.New("klean helmify cannot be combined with --split-dir, --in-place, --output-dir, --output, --diff or --report")
+	}
+	if cli.targetNS != "" {
+		if err := validateNamespaceName(cli.targetNS); err != nil {
+			return fmt.Errorf("--target-namespace: %w", err)
+		}
+	}
+	if cli.backup && !cli.inPlace {
+		return errors.New("--backup requires --in-place")
+	}
+	return nil
+}
+
+// useColor reports whether diffs written to stdout should be colorized.
+func useColor(mode, outputPath string, stdout io.Writer) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if (outputPath != "" && outputPath != "-") || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	file, ok := stdout.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// subcommand returns the subcommand in args and args without it. The subcommand is the first
+// argument that is neither a flag nor the value of one, so it may follow global flags (klean -q
+// helmify ...); any other first argument is an input file.
+func subcommand(args []string) (string, []string) {
+	fs := newFlagSet(defaultCleanupOptions(), &cliOptions{}, io.Discard)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-" || arg == "--" || !strings.HasPrefix(arg, "-") {
+			if arg == "helmify" {
+				return arg, slices.Delete(slices.Clone(args), i, i+1)
+			}
+			return "", args
+		}
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if f := fs.Lookup(name); f != nil {
+			if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !boolFlag.IsBoolFlag() {
+				i++ // Skip the value of the flag
+			}
+		}
+	}
+	return "", args
+}
+
+// run executes klean with the given arguments and returns the process exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	command, args := subcommand(args)
+	helmify := command == "helmify"
+
+	// First pass: validate the flags and find out which profile to load
+	cli := &cliOptions{helmify: helmify}
+	fs := newFlagSet(defaultCleanupOptions(), cli, stderr)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if cli.showVersion {
+		fmt.Fprintf(stdout, "klean %s (commit %s, built %s)\n", version, commit, date)
+		return exitOK
+	}
+	if err := validateCLIOptions(cli); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+
+	// Setup logging
+	log.SetOutput(stderr) // Log to stderr
+	log.SetPrefix("[Kleanup] ")
+	log.SetFlags(log.Ltime)
+	if cli.quiet {
+		log.SetOutput(io.Discard)
+	}
+	if cli.verbose {
+		log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
+	}
+
+	options := defaultCleanupOptions()
+	profilePath := cli.configPath
+	if profilePath == "" {
+		var err error
+		if profilePath, err = findProfile("."); err != nil {
+			fmt.Fprintf(stderr, "Error: searching for cleanup profile: %v\n", err)
+			return exitError
+		}
+	}
+	if profilePath != "" {
+		profile, err := loadProfile(profilePath)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+		profile.applyTo(options)
+	}
+
+	// Second pass: flags given on the command line override the profile
+	cli = &cliOptions{helmify: helmify}
+	fs = newFlagSet(options, cli, stderr)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if err := validateOptions(options); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitUsage
+	}
+	for kind := range options.KindOverrides {
+		if err := validateOptions(options.forKind(kind)); err != nil {
+			fmt.Fprintf(stderr, "Error: kind %s: %v\n", kind, err)
+			return exitUsage
+		}
+	}
+
+	if len(cli.schemaFiles) > 0 {
+		schemas, err := NewSchemaRegistry()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return exitError
+		}
+		for _, path := range cli.schemaFiles {
+			if err := schemas.LoadFile(path); err != nil {
+				fmt.Fprintf(stderr, "Error: %v\n", err)
+				return exitError
+			}
+		}
+		options.Schemas = schemas
+	}
+
+	if cli.diff || cli.report != "" || cli.auditLog != "" {
+		options.Report = &CleanupReport{}
+	}
+	if cli.splitDir != "" {
+		split, err := NewSplitWriter(cli.splitDir, cli.splitName)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+		options.Split = split
+	}
+	if cli.helmify {
+		if options.OutputFormat != outputFormatYAML {
+			fmt.Fprintf(stderr, "Error: klean helmify writes YAML templates and cannot use --output-format %s\n", options.OutputFormat)
+			return exitUsage
+		}
+		chart, err := NewChartWriter(cli.chartDir, cli.chartName)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return exitUsage
+		}
+		options.Chart = chart
+	}
+
+	if cli.verbose {
+		log.Printf("Options: %+v", *options)
+	}
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+
+	// Buffer the output so a failed run never leaves a truncated output file behind
+	var output bytes.Buffer
+
+	files, err := collectInputs(inputs, cli.recursive)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitError
+	}
+	if cli.inPlace && slices.ContainsFunc(files, func(input inputFile) bool { return input.path == "-" }) {
+		fmt.Fprintf(stderr, "Error: --in-place cannot rewrite stdin\n")
+		return exitUsage
+	}
+
+	// The namespaces and owners of the whole input are known before the first file is cleaned
+	crossFile := options.CollapseOwned && len(files) > 1
+	var stdinData []byte
+	if (cli.targetNS != "" || crossFile) && slices.ContainsFunc(files, func(input inputFile) bool { return input.path == "-" }) {
+		if stdinData, err = io.ReadAll(stdin); err != nil {
+			fmt.Fprintf(stderr, "Error: error reading stdin: %v\n", err)
+			return exitError
+		}
+		stdin = bytes.NewReader(stdinData) // Read again by the cleanup
+	}
+	if crossFile {
+		options.Owners = inputOwners(files, stdinData)
+	}
+	if len(files) > 1 {
+		// Pods of one controller in several files are reverted to a single controller
+		options.Reconstructed = map[string]bool{}
+	}
+	if cli.targetNS != "" {
+		// Every namespace of the input moves to the target, unless --namespace-map says otherwise
+		for _, namespace := range inputNamespaces(files, stdinData) {
+			if _, mapped := options.NamespaceMap[namespace]; !mapped {
+				if options.NamespaceMap == nil {
+					options.NamespaceMap = map[string]string{}
+				}
+				options.NamespaceMap[namespace] = cli.targetNS
+			}
+		}
+	}
+
+	log.Println("Starting cleanup...")
+	var failures []error // Per-file failures, reported once every file has been processed
+	for _, input := range files {
+		fileOpts := options
+		if cli.inPlace || cli.outputDir != "" {
+			fileOpts = fileOptions(options, input)
+		}
+		var document bytes.Buffer
+		err := cleanupInput(input.path, stdin, &document, fileOpts)
+		if err == nil {
+			switch {
+			case cli.inPlace:
+				err = rewriteFile(input.path, document.Bytes(), cli.backup)
+			case cli.outputDir != "":
+				var target string
+				if target, err = mirrorPath(cli.outputDir, input); err == nil {
+					err = writeMirroredFile(target, document.Bytes())
+				}
+			default:
+				// Each input is encoded separately, so join YAML documents with a document separator
+				if options.OutputFormat == outputFormatYAML && output.Len() > 0 && document.Len() > 0 {
+					output.WriteString("---\n")
+				}
+				output.Write(document.Bytes())
+			}
+		}
+		if err != nil {
+			if !cli.perFile() {
+				fmt.Fprintf(stderr, "Error: %v\n", err)
+				return exitError
+			}
+			log.Printf("Skipping file: %v", err)
+			failures = append(failures, err)
+		}
+	}
+
+	if cli.auditLog != "" {
+		if err := appendAuditLog(cli.auditLog, options.Report); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return exitError
+		}
+	}
+
+	// Dry runs replace the cleaned manifests with what cleaning changed
+	switch {
+	case cli.diff:
+		output.Reset()
+		if err := writeDiff(&output, options.Report, cli.diffFormat, useColor(cli.color, cli.outputPath, stdout)); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return exitError
+		}
+	case cli.report != "":
+		output.Reset()
+		if err := writeJSONReport(&output, options.Report); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return exitError
+		}
+	}
+
+	if err := writeOutput(cli.outputPath, stdout, output.Bytes()); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return exitError
+	}
+	if options.Split != nil {
+		log.Printf("Wrote %d files to %s", len(options.Split.Files), options.Split.Dir)
+	}
+	if options.Chart != nil {
+		if err := options.Chart.Close(); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return exitError
+		}
+		log.Printf("Wrote chart %s with %d templates to %s", options.Chart.Name, len(options.Chart.objects), options.Chart.Dir)
+	}
+	if cli.kustomize {
+		if err := writeKustomization(options.Split, cli.kustLabels, options.OutputFormat); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return exitError
+		}
+	}
+	if len(failures) > 0 {
+		fmt.Fprintf(stderr, "Error: %d of %d files could not be cleaned:\n", len(failures), len(files))
+		for _, failure := range failures {
+			fmt.Fprintf(stderr, "  %v\n", failure) // Errors name the file they concern
+		}
+		return exitError
+	}
+	log.Println("Cleanup finished successfully.")
+	return exitOK
+}
+
+// cleanupInput cleans a single input file, or stdin when inputPath is "-".
+func cleanupInput(inputPath string, stdin io.Reader, output io.Writer, options *CleanupOptions) error {
+	if inputPath == "-" {
+		log.Println("Reading from stdin...")
+		return cleanupManifest(stdin, output, options)
+	}
+
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("error opening input file '%s': %w", inputPath, err)
+	}
+	defer file.Close()
+
+	log.Printf("Reading from file: %s", inputPath)
+	if err := cleanupManifest(file, output, options); err != nil {
+		return fmt.Errorf("%s: %w", inputPath, err)
+	}
+	return nil
+}
+
+// inputNamespaces returns the namespaces of the objects in files. Files that cannot be read or
+// decoded are skipped here; cleaning them reports the error. stdin is the content of stdin.
+func inputNamespaces(files []inputFile, stdin []byte) []string {
+	var namespaces []string
+	for _, input := range files {
+		var found []string
+		if input.path == "-" {
+			found, _ = manifestNamespaces(bytes.NewReader(stdin))
+		} else if file, err := os.Open(input.path); err == nil {
+			found, _ = manifestNamespaces(file)
+			file.Close()
+		}
+		for _, namespace := range found {
+			if !slices.Contains(namespaces, namespace) {
+				namespaces = append(namespaces, namespace)
+			}
+		}
+	}
+	return namespaces
+}
+
+// inputOwners indexes the objects in files, so children are collapsed into owners found in other
+// files. Files that cannot be read or decoded are skipped here; cleaning them reports the error.
+func inputOwners(files []inputFile, stdin []byte) *ownerIndex {
+	index := newOwnerIndex(nil)
+	for _, input := range files {
+		if input.path == "-" {
+			index.addManifest(bytes.NewReader(stdin))
+		} else if file, err := os.Open(input.path); err == nil {
+			index.addManifest(file)
+			file.Close()
+		}
+	}
+	return index
+}
+
+// appendAuditLog appends the changes collected in report to the audit log at path.
+func appendAuditLog(path string, report *CleanupReport) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening audit log '%s': %w", path, err)
+	}
+	if err := writeAuditLog(file, report); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing audit log '%s': %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error writing audit log '%s': %w", path, err)
+	}
+	log.Printf("Appended changes to audit log: %s", path)
+	return nil
+}
+
+// writeOutput writes data to outputPath, or to stdout when no path is given.
+func writeOutput(outputPath string, stdout io.Writer, data []byte) error {
+	if outputPath == "" || outputPath == "-" {
+		_, err := stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(outputPath, data, 0o644); err != nil {
+		return fmt.Errorf("error writing output file '%s': %w", outputPath, err)
+	}
+	log.Printf("Wrote output to file: %s", outputPath)
+	return nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
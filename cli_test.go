@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const cliTestManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: shop
+  labels:
+    app: web
+    team: storefront
+data:
+  key: value
+`
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "input.yaml")
+	if err := os.WriteFile(inputPath, []byte(cliTestManifest), 0o644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		args             []string
+		stdin            string
+		expectedCode     int
+		expectedContains []string
+		expectedMissing  []string
+	}{
+		{
+			name:             "reads stdin by default",
+			stdin:            cliTestManifest,
+			expectedCode:     exitOK,
+			expectedContains: []string{"name: settings"},
+			expectedMissing:  []string{"namespace: shop"},
+		},
+		{
+			name:             "keeps namespace when disabled",
+			args:             []string{"--remove-namespace=false", "-"},
+			stdin:            cliTestManifest,
+			expectedCode:     exitOK,
+			expectedContains: []string{"namespace: shop"},
+		},
+		{
+			name:             "removes repeated labels",
+			args:             []string{"--remove-label", "team", "--remove-label=app", inputPath},
+			expectedCode:     exitOK,
+			expectedContains: []string{"name: settings"},
+			expectedMissing:  []string{"team: storefront", "app: web"},
+		},
+		{
+			name:             "joins multiple inputs with a separator",
+			args:             []string{inputPath, inputPath},
+			expectedCode:     exitOK,
+			expectedContains: []string{"---\n"},
+		},
+		{
+			name:             "prints a unified diff instead of the manifest",
+			args:             []string{"--diff", inputPath},
+			expectedCode:     exitOK,
+			expectedContains: []string{"--- a/v1/ConfigMap/shop/settings", "-  namespace: shop", "   name: settings"},
+			expectedMissing:  []string{"\x1b["},
+		},
+		{
+			name:             "prints a structured diff",
+			args:             []string{"--diff", "--diff-format", "structured", "--color", "never", inputPath},
+			expectedCode:     exitOK,
+			expectedContains: []string{"  - metadata.namespace: \"shop\"  (GenericMetadataCleaner:option)"},
+		},
+		{
+			name:             "prints a JSON report",
+			args:             []string{"--report", "json", "--remove-label", "team", inputPath},
+			expectedCode:     exitOK,
+			expectedContains: []string{`"path": "metadata.labels.team"`, `"reason": "cleanLabels:option"`},
+			expectedMissing:  []string{"apiVersion: v1"},
+		},
+		{name: "rejects diff with report", args: []string{"--diff", "--report", "json"}, expectedCode: exitUsage},
+		{name: "rejects invalid diff format", args: []string{"--diff-format", "side-by-side"}, expectedCode: exitUsage},
+		{name: "rejects invalid state mode", args: []string{"--state-mode", "Everything"}, expectedCode: exitUsage},
+		{name: "rejects invalid key order", args: []string{"--key-order", "alphabetical"}, expectedCode: exitUsage},
+		{name: "rejects invalid output format", args: []string{"--output-format", "xml"}, expectedCode: exitUsage},
+		{name: "rejects invalid volume binding", args: []string{"--volume-binding", "keep"}, expectedCode: exitUsage},
+		{name: "rejects in-place with output dir", args: []string{"--in-place", "--output-dir", dir}, expectedCode: exitUsage},
+		{name: "rejects backup without in-place", args: []string{"--backup"}, expectedCode: exitUsage},
+		{name: "rejects split dir with output file", args: []string{"--split-dir", dir, "-o", "out.yaml"}, expectedCode: exitUsage},
+		{name: "rejects kustomize without split dir", args: []string{"--kustomize"}, expectedCode: exitUsage},
+		{name: "rejects invalid kustomize labels field", args: []string{"--split-dir", dir, "--kustomize", "--kustomize-labels", "tags"}, expectedCode: exitUsage},
+		{name: "rejects chart dir without helmify", args: []string{"--chart-dir", dir}, expectedCode: exitUsage},
+		{name: "rejects invalid split template", args: []string{"--split-dir", dir, "--split-template", "{{.Kind"}, expectedCode: exitUsage},
+		{name: "rejects in-place on stdin", args: []string{"--in-place", "-"}, expectedCode: exitUsage},
+		{
+			name:             "writes JSON Lines without document separators",
+			args:             []string{"--output-format", "jsonl", inputPath, inputPath},
+			expectedCode:     exitOK,
+			expectedContains: []string{`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings"`},
+			expectedMissing:  []string{"---"},
+		},
+		{
+			name:             "moves objects to the target namespace",
+			args:             []string{"--target-namespace", "staging"},
+			stdin:            cliTestManifest,
+			expectedCode:     exitOK,
+			expectedContains: []string{"namespace: staging"},
+			expectedMissing:  []string{"namespace: shop"},
+		},
+		{name: "rejects invalid namespace map", args: []string{"--namespace-map", "shop"}, expectedCode: exitUsage},
+		{name: "rejects invalid target namespace", args: []string{"--target-namespace", "Staging"}, expectedCode: exitUsage},
+		{name: "rejects unknown flag", args: []string{"--no-such-flag"}, expectedCode: exitUsage},
+		{name: "fails on missing input file", args: []string{filepath.Join(dir, "missing.yaml")}, expectedCode: exitError},
+		{name: "fails on invalid YAML", stdin: "kind: [", expectedCode: exitError},
+		{name: "prints version", args: []string{"--version"}, expectedCode: exitOK, expectedContains: []string{"klean dev"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"--quiet"}, tt.args...), strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.expectedCode {
+				t.Fatalf("Expected exit code %d, got %d (stderr: %s)", tt.expectedCode, code, stderr.String())
+			}
+			for _, s := range tt.expectedContains {
+				if !strings.Contains(stdout.String(), s) {
+					t.Errorf("Expected output to contain %q, got:\n%s", s, stdout.String())
+				}
+			}
+			for _, s := range tt.expectedMissing {
+				if strings.Contains(stdout.String(), s) {
+					t.Errorf("Expected output not to contain %q, got:\n%s", s, stdout.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRunOutputFile(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "clean.yaml")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--quiet", "-o", outputPath}, strings.NewReader(cliTestManifest), &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitOK, code, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("Expected nothing on stdout, got:\n%s", stdout.String())
+	}
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("failed to read output file: %v", err)
+	}
+	if !strings.Contains(string(data), "name: settings") {
+		t.Errorf("Expected output file to contain the cleaned ConfigMap, got:\n%s", data)
+	}
+}
+
+func TestRunAuditLog(t *testing.T) {
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--quiet", "--audit-log", auditPath}, strings.NewReader(cliTestManifest), &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitOK, code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "name: settings") {
+		t.Errorf("Expected the cleaned manifest on stdout, got:\n%s", stdout.String())
+	}
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	expected := `{"object":"v1/ConfigMap/shop/settings","path":"metadata.namespace","oldValue":"shop","reason":"GenericMetadataCleaner:option"}`
+	if !strings.Contains(string(data), expected+"\n") {
+		t.Errorf("Expected audit log to contain %s, got:\n%s", expected, data)
+	}
+}
+
+func TestRunRecursive(t *testing.T) {
+	const deployment = `# Web frontend
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  uid: 0b7e2f4c
+spec:
+  replicas: 2
+`
+	setup := func(t *testing.T) string {
+		dir := t.TempDir()
+		files := map[string]string{
+			"apps/web.yaml":          deployment,
+			"apps/settings.json":     `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings","namespace":"shop"}}`,
+			"apps/broken.yml":        "kind: [",
+			"apps/README.md":         "not a manifest",
+			".git/config.yaml":       "kind: [",
+			"settings/.kleanup.yaml": "apiVersion: kleanup.opscalehub.io/v1alpha1\nkind: CleanupProfile\n",
+		}
+		for name, content := range files {
+			path := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				t.Fatalf("failed to create directory: %v", err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatalf("failed to write %s: %v", name, err)
+			}
+		}
+		return dir
+	}
+	readFile := func(t *testing.T, path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		return string(data)
+	}
+	cleanedDeployment := "# Web frontend\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\nspec:\n  replicas: 2\n"
+
+	t.Run("rewrites files in place with backups", func(t *testing.T) {
+		dir := setup(t)
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"--quiet", "-R", "--in-place", "--backup", dir}, strings.NewReader(""), &stdout, &stderr)
+		if code != exitError {
+			t.Fatalf("Expected exit code %d for the broken file, got %d", exitError, code)
+		}
+		if !strings.Contains(stderr.String(), "1 of 3 files could not be cleaned") || !strings.Contains(stderr.String(), "broken.yml") {
+			t.Errorf("Expected a summary naming the broken file, got:\n%s", stderr.String())
+		}
+		if actual := readFile(t, filepath.Join(dir, "apps/web.yaml")); actual != cleanedDeployment {
+			t.Errorf("Unexpected rewritten file.\nExpected:\n%s\nActual:\n%s", cleanedDeployment, actual)
+		}
+		if actual := readFile(t, filepath.Join(dir, "apps/web.yaml.bak")); actual != deployment {
+			t.Errorf("Expected the backup to hold the original content, got:\n%s", actual)
+		}
+		if actual := readFile(t, filepath.Join(dir, "apps/settings.json")); !strings.HasPrefix(actual, "{\n") || strings.Contains(actual, "shop") {
+			t.Errorf("Expected a cleaned JSON file, got:\n%s", actual)
+		}
+		if actual := readFile(t, filepath.Join(dir, "apps/broken.yml")); actual != "kind: [" {
+			t.Errorf("Expected the broken file to be left alone, got:\n%s", actual)
+		}
+	})
+
+	t.Run("keeps YAML that is not a manifest", func(t *testing.T) {
+		dir := t.TempDir()
+		values := "# Chart values\nreplicaCount: 2\nimage:\n  repository: web\n"
+		notes := "# Nothing here yet\n"
+		mixed := "# Settings\nregion: eu\n---\n" + deployment
+		for name, content := range map[string]string{"values.yaml": values, "notes.yaml": notes, "mixed.yaml": mixed} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+				t.Fatalf("failed to write %s: %v", name, err)
+			}
+		}
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"--quiet", "-R", "--in-place", dir}, strings.NewReader(""), &stdout, &stderr); code != exitOK {
+			t.Fatalf("Expected exit code %d, got %d: %s", exitOK, code, stderr.String())
+		}
+		if actual := readFile(t, filepath.Join(dir, "values.yaml")); actual != values {
+			t.Errorf("Expected values.yaml to be left alone, got:\n%s", actual)
+		}
+		if actual := readFile(t, filepath.Join(dir, "notes.yaml")); actual != notes {
+			t.Errorf("Expected notes.yaml to be left alone, got:\n%s", actual)
+		}
+		expected := "# Settings\nregion: eu\n---\n" + cleanedDeployment
+		if actual := readFile(t, filepath.Join(dir, "mixed.yaml")); actual != expected {
+			t.Errorf("Unexpected rewritten file.\nExpected:\n%s\nActual:\n%s", expected, actual)
+		}
+	})
+
+	t.Run("mirrors files into an output directory", func(t *testing.T) {
+		dir := setup(t)
+		outputDir := filepath.Join(t.TempDir(), "clean")
+		var stdout, stderr bytes.Buffer
+		run([]string{"--quiet", "-R", "--output-dir", outputDir, dir}, strings.NewReader(""), &stdout, &stderr)
+		if actual := readFile(t, filepath.Join(outputDir, "apps/web.yaml")); actual != cleanedDeployment {
+			t.Errorf("Unexpected mirrored file.\nExpected:\n%s\nActual:\n%s", cleanedDeployment, actual)
+		}
+		if actual := readFile(t, filepath.Join(dir, "apps/web.yaml")); actual != deployment {
+			t.Errorf("Expected the input to be left alone, got:\n%s", actual)
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, ".git")); err == nil {
+			t.Errorf("Expected hidden directories to be skipped")
+		}
+	})
+
+	t.Run("requires -R for directories", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"--quiet", setup(t)}, strings.NewReader(""), &stdout, &stderr); code != exitError {
+			t.Errorf("Expected exit code %d, got %d", exitError, code)
+		}
+	})
+}
+
+func TestRunCollapsesOwnedAcrossFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata: {name: web, namespace: shop}\nspec:\n  replicas: 3\n  template: {spec: {containers: [{name: web, image: nginx}]}}\n",
+		"hpa.yaml":        "apiVersion: autoscaling/v2\nkind: HorizontalPodAutoscaler\nmetadata: {name: web, namespace: shop}\nspec:\n  scaleTargetRef: {apiVersion: apps/v1, kind: Deployment, name: web}\n  maxReplicas: 5\n",
+		"pods.yaml":       "apiVersion: v1\nkind: Pod\nmetadata:\n  name: web-7d9f8b6c5d-abcde\n  namespace: shop\n  labels: {app: web, pod-template-hash: 7d9f8b6c5d}\n  ownerReferences: [{apiVersion: apps/v1, kind: ReplicaSet, name: web-7d9f8b6c5d, controller: true}]\nspec:\n  containers: [{name: web, image: nginx}]\n",
+	}
+	var args []string
+	for _, name := range []string{"pods.yaml", "deployment.yaml", "hpa.yaml"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(files[name]), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		args = append(args, path)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(append([]string{"--quiet"}, args...), strings.NewReader(""), &stdout, &stderr); code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitOK, code, stderr.String())
+	}
+	// The Pod is dropped rather than reverted to a second Deployment
+	if count := strings.Count(stdout.String(), "\nkind: Deployment"); count != 1 {
+		t.Errorf("Expected 1 Deployment in the output, got %d:\n%s", count, stdout.String())
+	}
+	if strings.Contains(stdout.String(), "replicas:") {
+		t.Errorf("Expected the replicas of the autoscaled Deployment to be removed, got:\n%s", stdout.String())
+	}
+}
+
+func TestRunRevertsPodsOnceAcrossFiles(t *testing.T) {
+	dir := t.TempDir()
+	pod := "apiVersion: v1\nkind: Pod\nmetadata:\n  name: web-7d9f8b6c5d-%s\n  namespace: shop\n  labels: {app: web, pod-template-hash: 7d9f8b6c5d}\n  ownerReferences: [{apiVersion: apps/v1, kind: ReplicaSet, name: web-7d9f8b6c5d, controller: true}]\nspec:\n  containers: [{name: web, image: nginx}]\n"
+	var args []string
+	for _, suffix := range []string{"abcde", "fghij"} {
+		path := filepath.Join(dir, suffix+".yaml")
+		if err := os.WriteFile(path, []byte(fmt.Sprintf(pod, suffix)), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		args = append(args, path)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(append([]string{"--quiet"}, args...), strings.NewReader(""), &stdout, &stderr); code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitOK, code, stderr.String())
+	}
+	if count := strings.Count(stdout.String(), "kind: Deployment"); count != 1 {
+		t.Errorf("Expected 1 Deployment in the output, got %d:\n%s", count, stdout.String())
+	}
+}
+
+func TestRunHelmify(t *testing.T) {
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "input.yaml")
+	if err := os.WriteFile(inputPath, []byte(cliTestManifest), 0o644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+	chartDir := filepath.Join(dir, "shop")
+
+	for _, args := range [][]string{
+		{"helmify", "-q", inputPath},
+		{"helmify", "-q", "--chart-dir", chartDir, "--output-format", "json", inputPath},
+		{"helmify", "-q", "--chart-dir", chartDir, "--split-dir", dir, inputPath},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, strings.NewReader(""), &stdout, &stderr); code != exitUsage {
+			t.Errorf("Expected exit code %d for %v, got %d", exitUsage, args, code)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"helmify", "-q", "--chart-dir", chartDir, inputPath},
+		{"-q", "--output-format", "yaml", "helmify", "--chart-dir", chartDir, inputPath},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, strings.NewReader(""), &stdout, &stderr); code != exitOK {
+			t.Fatalf("Expected exit code %d for %v, got %d (stderr: %s)", exitOK, args, code, stderr.String())
+		}
+		for _, path := range []string{"Chart.yaml", "values.yaml", "templates/configmap-settings.yaml"} {
+			if _, err := os.Stat(filepath.Join(chartDir, path)); err != nil {
+				t.Errorf("Expected chart file %s for %v: %v", path, args, err)
+			}
+		}
+		os.RemoveAll(chartDir)
+	}
+}
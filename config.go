@@ -0,0 +1,353 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Profile file format identifiers. Bump profileAPIVersion when the format changes incompatibly.
+const (
+	profileAPIVersion = "kleanup.opscalehub.io/v1alpha1"
+	profileKind       = "CleanupProfile"
+)
+
+// profileFileNames are the file names searched for, from the working directory upwards.
+var profileFileNames = []string{".kleanup.yaml", ".kleanup.yml"}
+
+// CleanupProfile is the declarative, versioned form of CleanupOptions stored in .kleanup.yaml.
+//
+//	apiVersion: kleanup.opscalehub.io/v1alpha1
+//	kind: CleanupProfile
+//	options:
+//	  removeNamespace: false
+//	  annotationPrefixes: ["example.com/"]
+//	kinds:
+//	  Pod:
+//	    revertToDeployment: false
+type CleanupProfile struct {
+	APIVersion string                     `yaml:"apiVersion"`
+	Kind       string                     `yaml:"kind"`
+	Options    ProfileOptions             `yaml:"options,omitempty"`
+	Kinds      map[string]*ProfileOptions `yaml:"kinds,omitempty"` // Per-kind overrides
+}
+
+// ProfileOptions mirrors CleanupOptions with optional values, so a profile only overrides what it sets.
+// Lists are appended to the existing values.
+type ProfileOptions struct {
+	RemoveManagedFields   *bool   `yaml:"removeManagedFields,omitempty"`
+	RemoveStatus          *bool   `yaml:"removeStatus,omitempty"`
+	RemoveNamespace       *bool   `yaml:"removeNamespace,omitempty"`
+	RemoveClusterName     *bool   `yaml:"removeClusterName,omitempty"`
+	RemoveEmpty           *bool   `yaml:"removeEmpty,omitempty"`
+	CleanupFinalizers     *bool   `yaml:"cleanupFinalizers,omitempty"`
+	RevertToDeployment    *bool   `yaml:"revertToDeployment,omitempty"`
+	PreserveResourceState *bool   `yaml:"preserveResourceState,omitempty"`
+	ResourceStateMode     *string `yaml:"resourceStateMode,omitempty"`
+	ExplodeLists          *bool   `yaml:"explodeLists,omitempty"`
+	KeepDefaults          *bool   `yaml:"keepDefaults,omitempty"`
+	CollapseOwned         *bool   `yaml:"collapseOwned,omitempty"`
+	RemoveSuspend         *bool   `yaml:"removeSuspend,omitempty"`
+	VolumeBinding         *string `yaml:"volumeBinding,omitempty"`
+	SkipSystemRBAC        *bool   `yaml:"skipSystemRBAC,omitempty"`
+	KeyOrder              *string `yaml:"keyOrder,omitempty"`     // Output formatting: ignored in per-kind overrides
+	OutputFormat          *string `yaml:"outputFormat,omitempty"` // Output formatting: ignored in per-kind overrides
+
+	RemoveLabels       []string `yaml:"removeLabels,omitempty"`
+	RemoveAnnotations  []string `yaml:"removeAnnotations,omitempty"`
+	AnnotationPrefixes []string `yaml:"annotationPrefixes,omitempty"` // Extra prefixes for cleanAnnotations
+	PodFields          []string `yaml:"podFields,omitempty"`          // Extra fields for cleanPodSpec
+	ContainerFields    []string `yaml:"containerFields,omitempty"`    // Extra fields for cleanContainerSpec
+
+	Rules []FieldRule `yaml:"rules,omitempty"` // Field rules applied after the built-in cleaners
+}
+
+// applyTo overrides options with every value set in the profile options.
+func (p *ProfileOptions) applyTo(options *CleanupOptions) {
+	if p == nil {
+		return
+	}
+	setBool := func(target *bool, value *bool) {
+		if value != nil {
+			*target = *value
+		}
+	}
+	setBool(&options.RemoveManagedFields, p.RemoveManagedFields)
+	setBool(&options.RemoveStatus, p.RemoveStatus)
+	setBool(&options.RemoveNamespace, p.RemoveNamespace)
+	setBool(&options.RemoveClusterName, p.RemoveClusterName)
+	setBool(&options.RemoveEmpty, p.RemoveEmpty)
+	setBool(&options.CleanupFinalizers, p.CleanupFinalizers)
+	setBool(&options.RevertToDeployment, p.RevertToDeployment)
+	setBool(&options.PreserveResourceState, p.PreserveResourceState)
+	setBool(&options.ExplodeLists, p.ExplodeLists)
+	setBool(&options.KeepDefaults, p.KeepDefaults)
+	setBool(&options.CollapseOwned, p.CollapseOwned)
+	setBool(&options.RemoveSuspend, p.RemoveSuspend)
+	setBool(&options.SkipSystemRBAC, p.SkipSystemRBAC)
+	if p.ResourceStateMode != nil {
+		options.ResourceStateMode = *p.ResourceStateMode
+	}
+	if p.VolumeBinding != nil {
+		options.VolumeBinding = *p.VolumeBinding
+	}
+	if p.KeyOrder != nil {
+		options.KeyOrder = *p.KeyOrder
+	}
+	if p.OutputFormat != nil {
+		options.OutputFormat = *p.OutputFormat
+	}
+
+	// Copy before appending so per-kind overrides never share backing arrays with the base options
+	appendAll := func(target *[]string, values []string) {
+		if len(values) > 0 {
+			*target = append(append([]string{}, *target...), values...)
+		}
+	}
+	appendAll(&options.RemoveLabels, p.RemoveLabels)
+	appendAll(&options.RemoveAnnotations, p.RemoveAnnotations)
+	appendAll(&options.ExtraAnnotationPrefixes, p.AnnotationPrefixes)
+	appendAll(&options.ExtraPodFields, p.PodFields)
+	appendAll(&options.ExtraContainerFields, p.ContainerFields)
+	if len(p.Rules) > 0 {
+		options.FieldRules = append(append([]FieldRule{}, options.FieldRules...), p.Rules...)
+	}
+}
+
+// applyTo applies the profile's base options and registers its per-kind overrides.
+func (p *CleanupProfile) applyTo(options *CleanupOptions) {
+	p.Options.applyTo(options)
+	if len(p.Kinds) > 0 && options.KindOverrides == nil {
+		options.KindOverrides = map[string]*ProfileOptions{}
+	}
+	for kind, override := range p.Kinds {
+		options.KindOverrides[kind] = override
+	}
+}
+
+// forKind returns the options to use for objects of the given kind.
+// Without an override for kind, options itself is returned.
+func (options *CleanupOptions) forKind(kind string) *CleanupOptions {
+	override, ok := options.KindOverrides[kind]
+	if !ok {
+		return options
+	}
+	resolved := *options
+	resolved.KindOverrides = nil // Overrides apply once, not to nested objects of other kinds
+	override.applyTo(&resolved)
+	return &resolved
+}
+
+// findProfile searches dir and its parents for a profile file and returns its path,
+// or an empty string if there is none.
+func findProfile(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, name := range profileFileNames {
+			candidate := filepath.Join(dir, name)
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				return candidate, nil
+			} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return "", err
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// loadProfile reads and validates the profile file at path.
+func loadProfile(path string) (*CleanupProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading profile '%s': %w", path, err)
+	}
+	profile, err := parseProfile(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile '%s': %w", path, err)
+	}
+	log.Printf("Loaded cleanup profile: %s", path)
+	return profile, nil
+}
+
+// parseProfile decodes and validates a profile document.
+func parseProfile(data []byte) (*CleanupProfile, error) {
+	// Check keys against the schema first: yaml.v2's strict mode errors do not suggest corrections
+	var raw map[interface{}]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if err := validateProfileKeys(normalizeValue(raw), reflect.TypeOf(CleanupProfile{}), ""); err != nil {
+		return nil, err
+	}
+
+	var profile CleanupProfile
+	if err := yaml.UnmarshalStrict(data, &profile); err != nil {
+		return nil, err
+	}
+
+	if profile.APIVersion != profileAPIVersion {
+		return nil, fmt.Errorf("unsupported apiVersion %q: expected %q", profile.APIVersion, profileAPIVersion)
+	}
+	if profile.Kind != profileKind {
+		return nil, fmt.Errorf("unsupported kind %q: expected %q", profile.Kind, profileKind)
+	}
+	if err := validateProfileOptions(&profile.Options, "options"); err != nil {
+		return nil, err
+	}
+	for kind, override := range profile.Kinds {
+		if err := validateProfileOptions(override, "kinds."+kind); err != nil {
+			return nil, err
+		}
+	}
+	return &profile, nil
+}
+
+// validateProfileOptions checks values that are valid YAML but not valid options.
+func validateProfileOptions(p *ProfileOptions, path string) error {
+	if p == nil {
+		return nil
+	}
+	if p.ResourceStateMode != nil {
+		if mode := *p.ResourceStateMode; mode != "Desired" && mode != "Runtime" {
+			return fmt.Errorf("%s.resourceStateMode: invalid value %q: must be 'Desired' or 'Runtime'", path, mode)
+		}
+	}
+	if p.VolumeBinding != nil {
+		if binding := *p.VolumeBinding; binding != volumeBindingUnbind && binding != volumeBindingPreserve {
+			return fmt.Errorf("%s.volumeBinding: invalid value %q: must be '%s' or '%s'", path, binding, volumeBindingUnbind, volumeBindingPreserve)
+		}
+	}
+	if p.KeyOrder != nil {
+		if order := *p.KeyOrder; order != keyOrderCanonical && order != keyOrderPreserve {
+			return fmt.Errorf("%s.keyOrder: invalid value %q: must be '%s' or '%s'", path, order, keyOrderCanonical, keyOrderPreserve)
+		}
+	}
+	if p.OutputFormat != nil {
+		if format := *p.OutputFormat; format != outputFormatYAML && format != outputFormatJSON && format != outputFormatJSONL {
+			return fmt.Errorf("%s.outputFormat: invalid value %q: must be '%s', '%s' or '%s'", path, format, outputFormatYAML, outputFormatJSON, outputFormatJSONL)
+		}
+	}
+	for i := range p.Rules {
+		if err := p.Rules[i].validate(); err != nil {
+			return fmt.Errorf("%s.rules[%d]: %w", path, i, err)
+		}
+	}
+	return nil
+}
+
+// validateProfileKeys walks a decoded profile and reports unknown keys, suggesting the closest known one.
+func validateProfileKeys(data interface{}, typ reflect.Type, path string) error {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		fields, ok := data.(map[string]interface{})
+		if !ok {
+			return nil // Type errors are reported by the strict decode
+		}
+		known := map[string]reflect.Type{}
+		for i := 0; i < typ.NumField(); i++ {
+			name := strings.Split(typ.Field(i).Tag.Get("yaml"), ",")[0]
+			known[name] = typ.Field(i).Type
+		}
+
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys) // Report the first unknown key deterministically
+
+		for _, key := range keys {
+			fieldPath := joinProfilePath(path, key)
+			fieldType, ok := known[key]
+			if !ok {
+				message := fmt.Sprintf("unknown field %q", fieldPath)
+				if suggestion := closestName(key, known); suggestion != "" {
+					message += fmt.Sprintf(" (did you mean %q?)", suggestion)
+				}
+				return errors.New(message)
+			}
+			if err := validateProfileKeys(fields[key], fieldType, fieldPath); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		items, ok := data.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, item := range items {
+			if err := validateProfileKeys(item, typ.Elem(), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		entries, ok := data.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for key, value := range entries {
+			if err := validateProfileKeys(value, typ.Elem(), joinProfilePath(path, key)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func joinProfilePath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// closestName returns the known name with the smallest edit distance to name, if it is close enough
+// to be a plausible typo.
+func closestName(name string, known map[string]reflect.Type) string {
+	best, bestDistance := "", len(name)/2+2
+	for candidate := range known {
+		distance := levenshtein(strings.ToLower(name), strings.ToLower(candidate))
+		if distance < bestDistance || (distance == bestDistance && candidate < best) {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// levenshtein computes the edit distance between two strings.
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
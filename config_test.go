@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testProfile = `apiVersion: kleanup.opscalehub.io/v1alpha1
+kind: CleanupProfile
+options:
+  removeNamespace: false
+  removeLabels: [team]
+  annotationPrefixes: [ci.example.com/]
+  podFields: [priority]
+  containerFields: [resources]
+kinds:
+  ConfigMap:
+    removeNamespace: true
+`
+
+func TestParseProfile(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		expectedError string
+	}{
+		{name: "valid profile", input: testProfile},
+		{
+			name:          "suggests correction for typo",
+			input:         strings.Replace(testProfile, "removeNamespace: false", "removeNamspace: false", 1),
+			expectedError: `unknown field "options.removeNamspace" (did you mean "removeNamespace"?)`,
+		},
+		{
+			name:          "suggests correction in per-kind override",
+			input:         strings.Replace(testProfile, "removeNamespace: true", "removeNamespaces: true", 1),
+			expectedError: `unknown field "kinds.ConfigMap.removeNamespaces" (did you mean "removeNamespace"?)`,
+		},
+		{
+			name:          "rejects unknown apiVersion",
+			input:         strings.Replace(testProfile, "v1alpha1", "v9", 1),
+			expectedError: `unsupported apiVersion "kleanup.opscalehub.io/v9"`,
+		},
+		{
+			name:          "rejects wrong value type",
+			input:         strings.Replace(testProfile, "removeNamespace: false", "removeNamespace: sometimes", 1),
+			expectedError: "cannot unmarshal",
+		},
+		{
+			name:          "rejects invalid state mode",
+			input:         testProfile + "  Pod:\n    resourceStateMode: Everything\n",
+			expectedError: `kinds.Pod.resourceStateMode: invalid value "Everything"`,
+		},
+		{
+			name:          "rejects invalid key order",
+			input:         testProfile + "  Pod:\n    keyOrder: alphabetical\n",
+			expectedError: `kinds.Pod.keyOrder: invalid value "alphabetical"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseProfile([]byte(tt.input))
+			if tt.expectedError == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Fatalf("Expected error containing %q, got: %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestProfileApplyTo(t *testing.T) {
+	profile, err := parseProfile([]byte(testProfile))
+	if err != nil {
+		t.Fatalf("parseProfile returned error: %v", err)
+	}
+	options := defaultCleanupOptions()
+	profile.applyTo(options)
+
+	if options.RemoveNamespace {
+		t.Errorf("Expected RemoveNamespace to be disabled by the profile")
+	}
+	if !reflect.DeepEqual(options.RemoveLabels, []string{"team"}) {
+		t.Errorf("Expected RemoveLabels [team], got %v", options.RemoveLabels)
+	}
+	if !reflect.DeepEqual(options.ExtraAnnotationPrefixes, []string{"ci.example.com/"}) {
+		t.Errorf("Expected extra annotation prefixes, got %v", options.ExtraAnnotationPrefixes)
+	}
+	if configMapOptions := options.forKind("ConfigMap"); !configMapOptions.RemoveNamespace {
+		t.Errorf("Expected ConfigMap override to enable RemoveNamespace")
+	}
+	if deploymentOptions := options.forKind("Deployment"); deploymentOptions != options {
+		t.Errorf("Expected kinds without override to use the base options")
+	}
+}
+
+func TestFindProfile(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "apps", "web")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("failed to create directories: %v", err)
+	}
+
+	if path, err := findProfile(nested); err != nil || path != "" {
+		// A profile above the temp dir would make this test meaningless, not wrong
+		t.Skipf("unexpected profile found above the test directory: %q (%v)", path, err)
+	}
+
+	profilePath := filepath.Join(root, ".kleanup.yaml")
+	if err := os.WriteFile(profilePath, []byte(testProfile), 0o644); err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+	path, err := findProfile(nested)
+	if err != nil {
+		t.Fatalf("findProfile returned error: %v", err)
+	}
+	if path != profilePath {
+		t.Errorf("Expected profile %s, got %s", profilePath, path)
+	}
+}
+
+func TestRunWithProfile(t *testing.T) {
+	profilePath := filepath.Join(t.TempDir(), "profile.yaml")
+	if err := os.WriteFile(profilePath, []byte(testProfile), 0o644); err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+	input := `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  namespace: shop
+  annotations:
+    ci.example.com/pipeline: "42"
+    example.com/owner: storefront
+  labels:
+    team: storefront
+spec:
+  priority: 0
+  containers:
+  - name: web
+    image: nginx
+    resources: {}
+`
+	tests := []struct {
+		name             string
+		args             []string
+		expectedContains []string
+		expectedMissing  []string
+	}{
+		{
+			name:             "profile options are applied",
+			args:             []string{"--config", profilePath},
+			expectedContains: []string{"namespace: shop", "example.com/owner"},
+			expectedMissing:  []string{"ci.example.com/pipeline", "team: storefront", "priority", "resources"},
+		},
+		{
+			name:             "flags override the profile",
+			args:             []string{"--config", profilePath, "--remove-namespace=true"},
+			expectedContains: []string{"example.com/owner"},
+			expectedMissing:  []string{"namespace: shop"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(append([]string{"--quiet"}, tt.args...), strings.NewReader(input), &stdout, &stderr)
+			if code != exitOK {
+				t.Fatalf("Expected exit code %d, got %d (stderr: %s)", exitOK, code, stderr.String())
+			}
+			for _, s := range tt.expectedContains {
+				if !strings.Contains(stdout.String(), s) {
+					t.Errorf("Expected output to contain %q, got:\n%s", s, stdout.String())
+				}
+			}
+			for _, s := range tt.expectedMissing {
+				if strings.Contains(stdout.String(), s) {
+					t.Errorf("Expected output not to contain %q, got:\n%s", s, stdout.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	yamlv3 "go.yaml.in/yaml/v3"
+)
+
+// controllerPodLabels are the labels controllers add to the Pods they create, by controller kind.
+// They are not part of the controller's own template.
+var controllerPodLabels = map[string][]string{
+	"Deployment":  {"pod-template-hash"},
+	"ReplicaSet":  {"pod-template-hash"},
+	"StatefulSet": {"controller-revision-hash", "statefulset.kubernetes.io/pod-name", "apps.kubernetes.io/pod-index"},
+	"DaemonSet":   {"controller-revision-hash", "pod-template-generation"},
+	"Job":         {"controller-uid", "job-name", "batch.kubernetes.io/controller-uid", "batch.kubernetes.io/job-name", "batch.kubernetes.io/job-completion-index"},
+}
+
+// controllerPodAnnotations are the annotations controllers add to the Pods they create.
+var controllerPodAnnotations = map[string][]string{
+	"Job": {"batch.kubernetes.io/job-completion-index", "batch.kubernetes.io/job-tracking"},
+}
+
+// controllerAPIVersions are the API versions of the controllers Pods are reconstructed into.
+var controllerAPIVersions = map[string]string{
+	"Deployment":  "apps/v1",
+	"ReplicaSet":  "apps/v1",
+	"StatefulSet": "apps/v1",
+	"DaemonSet":   "apps/v1",
+	"Job":         "batch/v1",
+}
+
+// cronJobJobName matches the names CronJobs give their Jobs: <cronjob>-<scheduled time in minutes>.
+var cronJobJobName = regexp.MustCompile(`^(.+)-(\d{8,})$`)
+
+// podController is the controller a Pod is reconstructed into.
+type podController struct {
+	kind string
+	name string
+}
+
+// key identifies the controller within a manifest, to de-duplicate Pods of the same controller.
+func (c podController) key(namespace string) string {
+	return c.kind + "/" + namespace + "/" + c.name
+}
+
+// findPodController returns the controller that created a Pod. ownerReferences are used when the
+// Pod has them: ReplicaSets named <deployment>-<pod-template-hash> belong to a Deployment. Jobs
+// named like CronJob runs are kept as Jobs, since the schedule of their CronJob cannot be
+// recovered from a Pod. Without ownerReferences the pod-template-hash label and the name of the
+// Pod identify a Deployment. ok is false for Pods without a known controller (e.g. static Pods
+// owned by a Node).
+func findPodController(obj *KubernetesObject) (controller podController, ok bool) {
+	labels, _ := obj.Metadata["labels"].(map[string]interface{})
+	hash, _ := labels["pod-template-hash"].(string)
+
+	owner, hasOwner := controllerOwner(obj)
+	if !hasOwner {
+		name, _ := obj.Metadata["name"].(string)
+		if hash == "" {
+			return controller, false
+		}
+		if base, ok := deriveBaseName(name, hash); ok {
+			return podController{kind: "Deployment", name: base}, true
+		}
+		log.Printf("Warning: Could not derive base name for Deployment from Pod name '%s'. Using default.", name)
+		return podController{kind: "Deployment", name: name + "-reverted"}, true
+	}
+
+	switch owner.kind {
+	case "ReplicaSet":
+		if hash != "" && strings.HasSuffix(owner.name, "-"+hash) {
+			return podController{kind: "Deployment", name: strings.TrimSuffix(owner.name, "-"+hash)}, true
+		}
+		return owner, true
+	case "Job":
+		if match := cronJobJobName.FindStringSubmatch(owner.name); match != nil {
+			log.Printf("Warning: Pod '%s' was created by a run of CronJob '%s', whose schedule cannot be recovered; reverting it to the Job '%s'", obj.Metadata["name"], match[1], owner.name)
+		}
+		return owner, true
+	case "StatefulSet", "DaemonSet":
+		return owner, true
+	}
+	return controller, false
+}
+
+// controllerOwner returns the owner reference of obj marked as its controller, or its only owner.
+func controllerOwner(obj *KubernetesObject) (podController, bool) {
+	references, _ := obj.Metadata["ownerReferences"].([]interface{})
+	var owners []podController
+	for _, reference := range references {
+		ref, ok := reference.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		kind, _ := ref["kind"].(string)
+		name, _ := ref["name"].(string)
+		if kind == "" || name == "" {
+			continue
+		}
+		if controller, _ := ref["controller"].(bool); controller {
+			return podController{kind: kind, name: name}, true
+		}
+		owners = append(owners, podController{kind: kind, name: name})
+	}
+	if len(owners) == 1 {
+		return owners[0], true
+	}
+	return podController{}, false
+}
+
+// reconstructController turns a Pod into the controller that created it, with the Pod's labels
+// (minus the ones the controller adds) as selector and template labels and the Pod's spec as
+// template. It returns false, leaving obj untouched, when the controller cannot be determined.
+func reconstructController(obj *KubernetesObject) (podController, bool) {
+	if obj == nil || obj.Kind != "Pod" || obj.Metadata == nil {
+		return podController{}, false
+	}
+	controller, ok := findPodController(obj)
+	if !ok {
+		log.Printf("Skipping Pod revert for '%s': No ownerReferences or 'pod-template-hash' label identify its controller.", obj.Metadata["name"])
+		return controller, false
+	}
+	log.Printf("Reverting Pod '%s' to %s '%s'", obj.Metadata["name"], controller.kind, controller.name)
+
+	// Labels and annotations added by the controller are not part of its template
+	const reason = "reconstructController:revert"
+	templateLabels := map[string]interface{}{}
+	if labels, ok := obj.Metadata["labels"].(map[string]interface{}); ok {
+		for _, key := range sortedKeys(labels) {
+			if slices.Contains(controllerPodLabels[controller.kind], key) {
+				obj.Changes.recordPath(appendKeyPath("metadata.labels", key), labels[key], reason)
+				continue
+			}
+			templateLabels[key] = labels[key]
+		}
+	}
+	if len(templateLabels) == 0 {
+		templateLabels["app"] = controller.name
+	}
+	templateMeta := map[string]interface{}{"labels": templateLabels}
+	if annotations, ok := obj.Metadata["annotations"].(map[string]interface{}); ok {
+		templateAnnotations := map[string]interface{}{}
+		for _, key := range sortedKeys(annotations) {
+			if slices.Contains(controllerPodAnnotations[controller.kind], key) {
+				obj.Changes.recordPath(appendKeyPath("metadata.annotations", key), annotations[key], reason)
+				continue
+			}
+			templateAnnotations[key] = annotations[key]
+		}
+		if len(templateAnnotations) > 0 {
+			templateMeta["annotations"] = templateAnnotations
+		}
+	}
+
+	// Record what the controller does not carry over; the pod spec moves into the template
+	for _, key := range sortedKeys(obj.Metadata) {
+		if key != "name" && key != "namespace" && key != "labels" && key != "annotations" {
+			obj.Changes.recordPath(appendKeyPath("metadata", key), obj.Metadata[key], reason)
+		}
+	}
+	topLevel := objectToMap(obj)
+	for _, key := range sortedKeys(topLevel) {
+		if key != "apiVersion" && key != "kind" && key != "metadata" && key != "spec" {
+			obj.Changes.recordPath(appendKeyPath("", key), topLevel[key], reason)
+		}
+	}
+
+	podSpec := obj.Spec
+	if podSpec == nil {
+		podSpec = map[string]interface{}{}
+	}
+	template := map[string]interface{}{"metadata": templateMeta, "spec": podSpec}
+	selector := map[string]interface{}{"matchLabels": copyLabels(templateLabels)}
+
+	var spec map[string]interface{}
+	switch controller.kind {
+	case "Deployment", "ReplicaSet":
+		spec = map[string]interface{}{"replicas": 1, "selector": selector, "template": template}
+	case "StatefulSet":
+		spec = map[string]interface{}{"replicas": 1, "selector": selector, "template": template}
+		// StatefulSet Pods get their hostname and the governing Service as subdomain
+		if serviceName, ok := podSpec["subdomain"].(string); ok && serviceName != "" {
+			spec["serviceName"] = serviceName
+		}
+		obj.Changes.remove(podSpec, "hostname", reason)
+		obj.Changes.remove(podSpec, "subdomain", reason)
+	case "DaemonSet":
+		spec = map[string]interface{}{"selector": selector, "template": template}
+		removeDaemonSetNodeAffinity(podSpec)
+	case "Job":
+		spec = map[string]interface{}{"template": template} // The selector is generated
+	}
+
+	if obj.node != nil {
+		obj.node = controllerNode(obj.node, spec)
+	}
+	obj.APIVersion = controllerAPIVersions[controller.kind]
+	obj.Kind = controller.kind
+	metadata := map[string]interface{}{"name": controller.name, "labels": copyLabels(templateLabels)}
+	if namespace, ok := obj.Metadata["namespace"]; ok {
+		metadata["namespace"] = namespace
+	}
+	obj.Metadata = metadata
+	obj.Spec = spec
+	obj.Status = nil
+	obj.Data = nil
+	obj.StringData = nil
+	obj.Type = ""
+	obj.Extra = nil
+	obj.Changes.invalidate() // Maps moved to new paths
+	return controller, true
+}
+
+// controllerNode returns the node to encode a controller reconstructed from the Pod decoded from
+// pod with: the Pod's node with its metadata and spec moved into the pod template, so they keep
+// their key order and comments. The other keys of spec, the controller's spec, come in canonical
+// order.
+func controllerNode(pod *yamlv3.Node, spec map[string]interface{}) *yamlv3.Node {
+	template := &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+	for _, key := range []string{"metadata", "spec"} {
+		if value := mappingValue(pod, key); value != nil {
+			template.Content = append(template.Content, scalarNode(key), value)
+		}
+	}
+
+	// The values of the new keys are placeholders, replaced by the controller's values when merged
+	specNode := &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+	keys := slices.Concat(canonicalKeys["spec"], sortedKeys(spec))
+	for i, key := range keys {
+		if _, ok := spec[key]; !ok || slices.Index(keys, key) < i {
+			continue
+		}
+		value := &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!null"}
+		if key == "template" {
+			value = template
+		}
+		specNode.Content = append(specNode.Content, scalarNode(key), value)
+	}
+
+	node := *pod
+	node.Content = slices.Clone(pod.Content)
+	setMappingValue(&node, "spec", specNode, "metadata")
+	return &node
+}
+
+// removeDaemonSetNodeAffinity removes the node affinity term the DaemonSet controller adds to pin
+// each of its Pods to a node (matchFields on metadata.name).
+func removeDaemonSetNodeAffinity(podSpec map[string]interface{}) {
+	affinity, _ := podSpec["affinity"].(map[string]interface{})
+	nodeAffinity, _ := affinity["nodeAffinity"].(map[string]interface{})
+	required, _ := nodeAffinity["requiredDuringSchedulingIgnoredDuringExecution"].(map[string]interface{})
+	terms, _ := required["nodeSelectorTerms"].([]interface{})
+	kept := terms[:0]
+	for _, term := range terms {
+		if termMap, ok := term.(map[string]interface{}); ok {
+			if fields, ok := termMap["matchFields"].([]interface{}); ok && len(fields) == 1 {
+				if field, ok := fields[0].(map[string]interface{}); ok && field["key"] == "metadata.name" {
+					continue
+				}
+			}
+		}
+		kept = append(kept, term)
+	}
+	if len(kept) == len(terms) {
+		return
+	}
+	if len(kept) > 0 {
+		required["nodeSelectorTerms"] = kept
+		return
+	}
+	delete(nodeAffinity, "requiredDuringSchedulingIgnoredDuringExecution")
+	if len(nodeAffinity) == 0 {
+		delete(affinity, "nodeAffinity")
+	}
+	if len(affinity) == 0 {
+		delete(podSpec, "affinity")
+	}
+}
+
+// copyLabels returns a copy of a label map.
+func copyLabels(labels map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(labels))
+	for key, value := range labels {
+		copied[key] = value
+	}
+	return copied
+}
+
+// podTemplateOf returns the pod template of a workload object, or nil.
+func podTemplateOf(obj *KubernetesObject) map[string]interface{} {
+	spec := obj.Spec
+	if obj.Kind == "CronJob" {
+		jobTemplate, _ := spec["jobTemplate"].(map[string]interface{})
+		spec, _ = jobTemplate["spec"].(map[string]interface{})
+	}
+	template, _ := spec["template"].(map[string]interface{})
+	return template
+}
+
+// cleanPodTemplate cleans the metadata and pod spec of a pod template, removing them when cleaning
+// leaves them empty.
+func cleanPodTemplate(template map[string]interface{}, options *CleanupOptions, changes *ChangeRecorder) {
+	if templateMeta, ok := template["metadata"].(map[string]interface{}); ok {
+		cleanTemplateMetadata(templateMeta, options, changes)
+		cleanedTemplateMeta := removeEmptyFields(templateMeta, changes)
+		if cleanedTemplateMeta == nil {
+			delete(template, "metadata")
+		} else if tm, ok := cleanedTemplateMeta.(map[string]interface{}); ok {
+			template["metadata"] = tm
+		}
+	}
+	if spec, ok := template["spec"].(map[string]interface{}); ok {
+		cleanPodSpec(spec, options, changes)
+		cleanedSpec := removeEmptyFields(spec, changes)
+		if cleanedSpec == nil {
+			delete(template, "spec")
+		} else if sp, ok := cleanedSpec.(map[string]interface{}); ok {
+			template["spec"] = sp
+		}
+	}
+}
+
+// collapsibleKinds are the kinds whose objects are created by a controller and are dropped when
+// that controller is part of the input.
+var collapsibleKinds = []string{"ReplicaSet", "Pod", "Job", "EndpointSlice", "ControllerRevision", "Endpoints"}
+
+// endpointSliceControllers are the managers of EndpointSlices that are recreated for a Service.
+var endpointSliceControllers = []string{"endpointslice-controller.k8s.io", "endpointslicemirroring-controller.k8s.io"}
+
+// ownerIndex indexes the objects of a manifest to find the children of controllers in it.
+type ownerIndex struct {
+	objects          map[string]bool // kind/namespace/name
+	uids             map[string]bool
+	selectorServices map[string]bool // namespace/name of Services with a selector
+	autoscaled       map[string]bool // kind/namespace/name of the scale targets of HorizontalPodAutoscalers
+}
+
+// newOwnerIndex indexes the objects of documents, including List items.
+func newOwnerIndex(documents []decodedDocument) *ownerIndex {
+	index := &ownerIndex{objects: map[string]bool{}, uids: map[string]bool{}, selectorServices: map[string]bool{}, autoscaled: map[string]bool{}}
+	for i := range documents {
+		index.add(&documents[i].obj)
+	}
+	return index
+}
+
+// add indexes obj and its List items.
+func (x *ownerIndex) add(obj *KubernetesObject) {
+	for i := range obj.Items {
+		x.add(&obj.Items[i])
+	}
+	namespace, _ := obj.Metadata["namespace"].(string)
+	name, _ := obj.Metadata["name"].(string)
+	if name == "" {
+		return
+	}
+	x.objects[obj.Kind+"/"+namespace+"/"+name] = true
+	if uid, ok := obj.Metadata["uid"].(string); ok && uid != "" {
+		x.uids[uid] = true
+	}
+	if _, ok := obj.Spec["selector"].(map[string]interface{}); ok && obj.Kind == "Service" {
+		x.selectorServices[namespace+"/"+name] = true
+	}
+	if target, ok := obj.Spec["scaleTargetRef"].(map[string]interface{}); ok && obj.Kind == "HorizontalPodAutoscaler" {
+		x.autoscaled[fmt.Sprintf("%v/%s/%v", target["kind"], namespace, target["name"])] = true
+	}
+}
+
+// addManifest indexes the objects of a manifest, up to the first document that cannot be decoded.
+func (x *ownerIndex) addManifest(input io.Reader) {
+	documents := newDocumentReader(bufio.NewReader(input))
+	for {
+		obj, _, err := documents.next()
+		if err != nil {
+			return // io.EOF, or an error reported when the manifest is cleaned
+		}
+		normalizeObject(&obj)
+		x.add(&obj)
+	}
+}
+
+// ownerOf returns the controller of obj, as kind/name, when it is part of the indexed input.
+// Only objects a controller recreates are considered: Pods, ReplicaSets, Jobs of CronJobs,
+// EndpointSlices and Endpoints of Services with a selector, and ControllerRevisions.
+func (x *ownerIndex) ownerOf(obj *KubernetesObject) string {
+	if x == nil || !slices.Contains(collapsibleKinds, obj.Kind) {
+		return ""
+	}
+	namespace, _ := obj.Metadata["namespace"].(string)
+	name, _ := obj.Metadata["name"].(string)
+	present := func(kind, name string) string {
+		if name != "" && x.objects[kind+"/"+namespace+"/"+name] {
+			return kind + "/" + name
+		}
+		return ""
+	}
+
+	references, _ := obj.Metadata["ownerReferences"].([]interface{})
+	for _, reference := range references {
+		ref, ok := reference.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if controller, _ := ref["controller"].(bool); !controller {
+			continue
+		}
+		kind, _ := ref["kind"].(string)
+		refName, _ := ref["name"].(string)
+		if owner := present(kind, refName); owner != "" {
+			return owner
+		}
+		if uid, _ := ref["uid"].(string); uid != "" && x.uids[uid] {
+			return kind + "/" + refName
+		}
+	}
+
+	labels, _ := obj.Metadata["labels"].(map[string]interface{})
+	switch obj.Kind {
+	case "Pod":
+		// The ReplicaSet or Job in between may be missing from the input
+		if controller, ok := findPodController(obj); ok {
+			if owner := present(controller.kind, controller.name); owner != "" || controller.kind != "Job" {
+				return owner
+			}
+			// A missing Job of a CronJob run would be rebuilt next to the CronJob
+			if match := cronJobJobName.FindStringSubmatch(controller.name); match != nil {
+				return present("CronJob", match[1])
+			}
+		}
+	case "Job":
+		if match := cronJobJobName.FindStringSubmatch(name); match != nil && len(references) == 0 {
+			return present("CronJob", match[1])
+		}
+	case "EndpointSlice":
+		service, _ := labels["kubernetes.io/service-name"].(string)
+		manager, _ := labels["endpointslice.kubernetes.io/managed-by"].(string)
+		if slices.Contains(endpointSliceControllers, manager) {
+			return present("Service", service)
+		}
+	case "Endpoints":
+		// Endpoints of Services without a selector are managed by hand
+		if name != "" && x.selectorServices[namespace+"/"+name] {
+			return "Service/" + name
+		}
+	}
+	return ""
+}
+
+// isAutoscaled reports whether a HorizontalPodAutoscaler of the indexed input scales obj.
+func (x *ownerIndex) isAutoscaled(obj *KubernetesObject) bool {
+	if x == nil {
+		return false
+	}
+	name, _ := obj.Metadata["name"].(string)
+	return x.autoscaled[obj.Kind+"/"+obj.namespace+"/"+name]
+}
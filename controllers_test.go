@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFindPodController(t *testing.T) {
+	newPod := func(labels map[string]interface{}, ownerKind, ownerName string) *KubernetesObject {
+		metadata := map[string]interface{}{"name": "pod-abc12", "labels": labels}
+		if ownerKind != "" {
+			metadata["ownerReferences"] = []interface{}{
+				map[string]interface{}{"apiVersion": "apps/v1", "kind": ownerKind, "name": ownerName, "controller": true},
+			}
+		}
+		return &KubernetesObject{APIVersion: "v1", Kind: "Pod", Metadata: metadata}
+	}
+	hashed := map[string]interface{}{"app": "web", "pod-template-hash": "7d9f8b6c5d"}
+
+	tests := []struct {
+		name         string
+		pod          *KubernetesObject
+		expectedKind string
+		expectedName string
+		expectedOK   bool
+	}{
+		{name: "replica set of a deployment", pod: newPod(hashed, "ReplicaSet", "web-7d9f8b6c5d"), expectedKind: "Deployment", expectedName: "web", expectedOK: true},
+		{name: "standalone replica set", pod: newPod(map[string]interface{}{"app": "web"}, "ReplicaSet", "web"), expectedKind: "ReplicaSet", expectedName: "web", expectedOK: true},
+		{name: "stateful set", pod: newPod(nil, "StatefulSet", "db"), expectedKind: "StatefulSet", expectedName: "db", expectedOK: true},
+		{name: "daemon set", pod: newPod(nil, "DaemonSet", "agent"), expectedKind: "DaemonSet", expectedName: "agent", expectedOK: true},
+		{name: "job", pod: newPod(nil, "Job", "migrate"), expectedKind: "Job", expectedName: "migrate", expectedOK: true},
+		{name: "job of a cron job", pod: newPod(nil, "Job", "report-28345678"), expectedKind: "Job", expectedName: "report-28345678", expectedOK: true},
+		{name: "static pod", pod: newPod(nil, "Node", "node-1"), expectedOK: false},
+		{name: "no owner without hash", pod: newPod(map[string]interface{}{"app": "web"}, "", ""), expectedOK: false},
+		{
+			name:         "no owner with hash",
+			pod:          &KubernetesObject{Kind: "Pod", Metadata: map[string]interface{}{"name": "web-7d9f8b6c5d-abc12", "labels": hashed}},
+			expectedKind: "Deployment",
+			expectedName: "web",
+			expectedOK:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller, ok := findPodController(tt.pod)
+			if ok != tt.expectedOK || controller.kind != tt.expectedKind || controller.name != tt.expectedName {
+				t.Errorf("Unexpected controller.\nExpected: %s %s %v\nActual: %s %s %v", tt.expectedKind, tt.expectedName, tt.expectedOK, controller.kind, controller.name, ok)
+			}
+		})
+	}
+}
+
+func TestCleanupManifestReconstructsControllers(t *testing.T) {
+	input := `apiVersion: v1
+kind: Pod
+metadata:
+  name: db-0
+  namespace: shop
+  labels:
+    app: db
+    controller-revision-hash: db-5f7
+    statefulset.kubernetes.io/pod-name: db-0
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: StatefulSet
+    name: db
+    controller: true
+spec:
+  hostname: db-0
+  subdomain: db-headless
+  containers:
+  - name: db
+    image: postgres
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: db-1
+  namespace: shop
+  labels:
+    app: db
+    controller-revision-hash: db-5f7
+    statefulset.kubernetes.io/pod-name: db-1
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: StatefulSet
+    name: db
+    controller: true
+spec:
+  hostname: db-1
+  subdomain: db-headless
+  containers:
+  - name: db
+    image: postgres
+`
+	expected := `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+  labels:
+    app: db
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: db
+  serviceName: db-headless
+  template:
+    metadata:
+      labels:
+        app: db
+    spec:
+      containers:
+      - name: db
+        image: postgres
+`
+	options := defaultCleanupOptions()
+	options.Report = &CleanupReport{}
+	var output bytes.Buffer
+	if err := cleanupManifest(strings.NewReader(input), &output, options); err != nil {
+		t.Fatalf("cleanupManifest returned error: %v", err)
+	}
+	if output.String() != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nActual:\n%s", expected, output.String())
+	}
+
+	// The second Pod is reported as dropped
+	if len(options.Report.Objects) != 2 {
+		t.Fatalf("Expected 2 object reports, got %d", len(options.Report.Objects))
+	}
+	removed := options.Report.Objects[1].Removed
+	if len(removed) != 1 || removed[0].Path != "" || removed[0].Reason != "PodCleaner:duplicate" {
+		t.Errorf("Expected the duplicate Pod to be reported as dropped, got %+v", removed)
+	}
+}
+
+func TestCleanupManifestCollapsesOwned(t *testing.T) {
+	input := `apiVersion: v1
+kind: Pod
+metadata:
+  name: web-7d9f8b6c5d-abcde
+  namespace: shop
+  labels: {app: web, pod-template-hash: 7d9f8b6c5d}
+  ownerReferences:
+  - {apiVersion: apps/v1, kind: ReplicaSet, name: web-7d9f8b6c5d, controller: true, uid: rs1}
+spec:
+  containers: [{name: web, image: nginx}]
+---
+apiVersion: v1
+kind: Service
+metadata: {name: web, namespace: shop}
+spec:
+  selector: {app: web}
+  ports: [{port: 80}]
+---
+apiVersion: v1
+kind: Endpoints
+metadata: {name: web, namespace: shop}
+subsets: [{addresses: [{ip: 10.0.0.1}]}]
+---
+apiVersion: discovery.k8s.io/v1
+kind: EndpointSlice
+metadata:
+  name: web-x7k2p
+  namespace: shop
+  labels: {kubernetes.io/service-name: web, endpointslice.kubernetes.io/managed-by: endpointslice-controller.k8s.io}
+addressType: IPv4
+endpoints: [{addresses: [10.0.0.1]}]
+---
+apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  name: web-7d9f8b6c5d
+  namespace: shop
+  ownerReferences:
+  - {apiVersion: apps/v1, kind: Deployment, name: web, controller: true, uid: d1}
+spec:
+  selector: {matchLabels: {app: web}}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata: {name: web, namespace: shop, uid: d1}
+spec:
+  selector: {matchLabels: {app: web}}
+  template:
+    metadata: {labels: {app: web}}
+    spec:
+      containers: [{name: web, image: nginx}]
+---
+apiVersion: batch/v1
+kind: Job
+metadata: {name: report-28930120, namespace: shop}
+spec:
+  template:
+    spec:
+      containers: [{name: report, image: busybox}]
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata: {name: report, namespace: shop}
+spec:
+  schedule: "0 * * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers: [{name: report, image: busybox}]
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: report-28930180-x2v9q
+  namespace: shop
+  labels: {job-name: report-28930180}
+  ownerReferences:
+  - {apiVersion: batch/v1, kind: Job, name: report-28930180, controller: true, uid: j2}
+spec:
+  restartPolicy: Never
+  containers: [{name: report, image: busybox}]
+---
+apiVersion: v1
+kind: Endpoints
+metadata: {name: external, namespace: shop}
+subsets: [{addresses: [{ip: 192.168.0.10}]}]
+`
+	tests := []struct {
+		name          string
+		collapseOwned bool
+		expectedKinds []string
+	}{
+		{
+			name:          "children of controllers in the input are dropped",
+			collapseOwned: true,
+			expectedKinds: []string{"Service", "Deployment", "CronJob", "Endpoints"},
+		},
+		{
+			name:          "disabled",
+			collapseOwned: false,
+			expectedKinds: []string{"Deployment", "Service", "Endpoints", "EndpointSlice", "ReplicaSet", "Deployment", "Job", "CronJob", "Job", "Endpoints"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := defaultCleanupOptions()
+			options.CollapseOwned = tt.collapseOwned
+			var output bytes.Buffer
+			if err := cleanupManifest(strings.NewReader(input), &output, options); err != nil {
+				t.Fatalf("cleanupManifest returned error: %v", err)
+			}
+			var kinds []string
+			for _, line := range strings.Split(output.String(), "\n") {
+				if kind, ok := strings.CutPrefix(line, "kind: "); ok {
+					kinds = append(kinds, kind)
+				}
+			}
+			if strings.Join(kinds, ",") != strings.Join(tt.expectedKinds, ",") {
+				t.Errorf("Unexpected kinds.\nExpected: %v\nActual: %v", tt.expectedKinds, kinds)
+			}
+			if tt.collapseOwned && !strings.Contains(output.String(), "192.168.0.10") {
+				t.Errorf("Expected the Endpoints of a Service without a selector to be kept, got:\n%s", output.String())
+			}
+		})
+	}
+}
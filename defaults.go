@@ -0,0 +1,279 @@
+package main
+
+import (
+	"strings"
+)
+
+// fieldDefault is a value the API server fills in when a field is omitted. A field is only removed
+// when its value equals the default, so deliberately configured values survive the cleanup.
+type fieldDefault struct {
+	path  string      // Dot notation, relative to the map the defaults apply to
+	value interface{} // The server-side default
+	// valueFrom computes defaults that depend on sibling fields, e.g. a Service port's targetPort.
+	// It receives the map the defaults apply to and overrides value when set.
+	valueFrom func(data map[string]interface{}) interface{}
+}
+
+// kindSpecDefaults lists the spec defaults applied by the API server, per kind.
+// Nested defaults come before their parents, so that e.g. a default strategy disappears entirely.
+var kindSpecDefaults = map[string][]fieldDefault{
+	"Deployment": {
+		{path: "strategy.rollingUpdate.maxSurge", value: "25%"},
+		{path: "strategy.rollingUpdate.maxUnavailable", value: "25%"},
+		{path: "strategy.type", value: "RollingUpdate"},
+		{path: "revisionHistoryLimit", value: 10},
+		{path: "progressDeadlineSeconds", value: 600},
+	},
+	"StatefulSet": {
+		{path: "updateStrategy.rollingUpdate.partition", value: 0},
+		{path: "updateStrategy.type", value: "RollingUpdate"},
+		{path: "podManagementPolicy", value: "OrderedReady"},
+		{path: "persistentVolumeClaimRetentionPolicy.whenDeleted", value: "Retain"},
+		{path: "persistentVolumeClaimRetentionPolicy.whenScaled", value: "Retain"},
+		{path: "revisionHistoryLimit", value: 10},
+	},
+	"DaemonSet": {
+		{path: "updateStrategy.rollingUpdate.maxSurge", value: 0},
+		{path: "updateStrategy.rollingUpdate.maxUnavailable", value: 1},
+		{path: "updateStrategy.type", value: "RollingUpdate"},
+		{path: "revisionHistoryLimit", value: 10},
+	},
+	"Job": {
+		{path: "backoffLimit", value: 6},
+		{path: "completionMode", value: "NonIndexed"},
+		// Without completions, parallelism Pods work through a queue: completions: 1 only goes
+		// with a single Pod, and parallelism: 1 only with a single completion
+		{path: "completions", valueFrom: defaultIfSingle("parallelism")},
+		{path: "parallelism", valueFrom: defaultIfSingle("completions")},
+		{path: "podReplacementPolicy", value: "TerminatingOrFailed"},
+		{path: "suspend", value: false},
+		{path: "manualSelector", value: false},
+	},
+	"CronJob": {
+		{path: "concurrencyPolicy", value: "Allow"},
+		{path: "failedJobsHistoryLimit", value: 1},
+		{path: "successfulJobsHistoryLimit", value: 3},
+		{path: "suspend", value: false},
+	},
+	"HorizontalPodAutoscaler": {
+		{path: "minReplicas", value: 1},
+	},
+	"PersistentVolumeClaim": {
+		{path: "volumeMode", value: "Filesystem"},
+	},
+	"PersistentVolume": {
+		{path: "volumeMode", value: "Filesystem"},
+	},
+	"Service": {
+		{path: "type", value: "ClusterIP"},
+		{path: "sessionAffinity", value: "None"},
+		{path: "internalTrafficPolicy", value: "Cluster"},
+		{path: "externalTrafficPolicy", value: "Cluster"},
+		{path: "ipFamilyPolicy", value: "SingleStack"},
+		{path: "allocateLoadBalancerNodePorts", value: true},
+		{path: "publishNotReadyAddresses", value: false},
+	},
+}
+
+// specDefaults returns the top-level spec fields of kind that hold their API server default, as
+// listed in kindSpecDefaults. A map counts when every field in it holds its default.
+func specDefaults(kind string, spec map[string]interface{}) map[string]bool {
+	defaults := kindSpecDefaults[kind]
+	if len(defaults) == 0 || spec == nil {
+		return nil
+	}
+	remaining := deepCopyValue(spec).(map[string]interface{})
+	removeDefaults(remaining, defaults, nil, "")
+	fields := map[string]bool{}
+	for key := range spec {
+		if _, kept := remaining[key]; !kept {
+			fields[key] = true
+		}
+	}
+	return fields
+}
+
+// servicePortDefaults apply to every entry of a Service's spec.ports.
+var servicePortDefaults = []fieldDefault{
+	{path: "protocol", value: "TCP"},
+	{path: "targetPort", valueFrom: func(port map[string]interface{}) interface{} { return port["port"] }},
+}
+
+// podSpecDefaults apply to Pod specs and pod templates.
+var podSpecDefaults = []fieldDefault{
+	{path: "dnsPolicy", value: "ClusterFirst"},
+	{path: "restartPolicy", value: "Always"},
+	{path: "schedulerName", value: "default-scheduler"},
+	{path: "terminationGracePeriodSeconds", value: 30},
+	{path: "enableServiceLinks", value: true},
+	{path: "preemptionPolicy", value: "PreemptLowerPriority"},
+	{path: "priority", value: 0}, // Resolved from priorityClassName by admission
+	{path: "serviceAccountName", value: "default"},
+	{path: "serviceAccount", value: "default"}, // Deprecated alias of serviceAccountName
+	{path: "hostNetwork", value: false},
+	{path: "hostPID", value: false},
+	{path: "hostIPC", value: false},
+	{path: "shareProcessNamespace", value: false},
+	{path: "setHostnameAsFQDN", value: false},
+}
+
+// containerDefaults apply to containers and init containers.
+var containerDefaults = []fieldDefault{
+	{path: "terminationMessagePath", value: "/dev/termination-log"},
+	{path: "terminationMessagePolicy", value: "File"},
+	{path: "imagePullPolicy", valueFrom: defaultImagePullPolicy},
+	{path: "tty", value: false},
+	{path: "stdin", value: false},
+	{path: "stdinOnce", value: false},
+}
+
+// probeDefaults apply to liveness, readiness and startup probes.
+var probeDefaults = []fieldDefault{
+	{path: "httpGet.scheme", value: "HTTP"},
+	{path: "timeoutSeconds", value: 1},
+	{path: "periodSeconds", value: 10},
+	{path: "successThreshold", value: 1},
+	{path: "failureThreshold", value: 3},
+}
+
+// containerPortDefaults apply to every entry of a container's ports.
+var containerPortDefaults = []fieldDefault{
+	{path: "protocol", value: "TCP"},
+}
+
+// volumeDefaults apply to every entry of a pod's volumes.
+var volumeDefaults = []fieldDefault{
+	{path: "configMap.defaultMode", value: 420},
+	{path: "secret.defaultMode", value: 420},
+	{path: "projected.defaultMode", value: 420},
+	{path: "downwardAPI.defaultMode", value: 420},
+	{path: "hostPath.type", value: ""},
+}
+
+// defaultTolerations are added to every Pod by the DefaultTolerationSeconds admission plugin.
+var defaultTolerations = []interface{}{
+	map[string]interface{}{"effect": "NoExecute", "key": "node.kubernetes.io/not-ready", "operator": "Exists", "tolerationSeconds": 300},
+	map[string]interface{}{"effect": "NoExecute", "key": "node.kubernetes.io/unreachable", "operator": "Exists", "tolerationSeconds": 300},
+}
+
+// removeDefaultTolerations removes the tolerations added by admission from a pod spec and records
+// them with reason.
+func removeDefaultTolerations(spec map[string]interface{}, changes *ChangeRecorder, reason string) {
+	tolerations, ok := spec["tolerations"].([]interface{})
+	if !ok {
+		return
+	}
+	kept := make([]interface{}, 0, len(tolerations))
+	for i, toleration := range tolerations {
+		isDefault := false
+		for _, d := range defaultTolerations {
+			if valuesEqual(d, toleration) {
+				isDefault = true
+				break
+			}
+		}
+		if !isDefault {
+			kept = append(kept, toleration)
+		} else {
+			changes.recordItem(spec, "tolerations", tolerations, i, reason)
+		}
+	}
+	if len(kept) == 0 {
+		delete(spec, "tolerations")
+	} else {
+		spec["tolerations"] = kept
+	}
+}
+
+// defaultImagePullPolicy returns the pull policy the API server assigns to a container:
+// Always for images without a tag or tagged :latest, IfNotPresent otherwise.
+func defaultImagePullPolicy(container map[string]interface{}) interface{} {
+	image, ok := container["image"].(string)
+	if !ok {
+		return nil
+	}
+	if strings.Contains(image, "@") {
+		return "IfNotPresent" // Pinned by digest
+	}
+	name := image[strings.LastIndex(image, "/")+1:] // A registry port is not a tag
+	tag := ""
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		tag = name[i+1:]
+	}
+	if tag == "" || tag == "latest" {
+		return "Always"
+	}
+	return "IfNotPresent"
+}
+
+// defaultIfSingle returns a valueFrom for a field defaulting to 1 that only holds its default when
+// the sibling field is absent or 1 as well.
+func defaultIfSingle(sibling string) func(data map[string]interface{}) interface{} {
+	return func(data map[string]interface{}) interface{} {
+		if value, ok := data[sibling]; ok && !valuesEqual(1, value) {
+			return nil
+		}
+		return 1
+	}
+}
+
+// removeDefaults removes every field of data that still holds its default value and records the
+// removals with reason. Maps that become empty because of a removal are removed as well.
+func removeDefaults(data map[string]interface{}, defaults []fieldDefault, changes *ChangeRecorder, reason string) {
+	if data == nil {
+		return
+	}
+	for _, d := range defaults {
+		expected := d.value
+		if d.valueFrom != nil {
+			expected = d.valueFrom(data)
+		}
+		if expected == nil {
+			continue
+		}
+
+		keys := strings.Split(d.path, ".")
+		parents := []map[string]interface{}{data}
+		for _, key := range keys[:len(keys)-1] {
+			next, ok := parents[len(parents)-1][key].(map[string]interface{})
+			if !ok {
+				break
+			}
+			parents = append(parents, next)
+		}
+		if len(parents) != len(keys) {
+			continue // An intermediate map is missing
+		}
+
+		parent := parents[len(parents)-1]
+		value, exists := parent[keys[len(keys)-1]]
+		if !exists || !valuesEqual(expected, value) {
+			continue
+		}
+		changes.remove(parent, keys[len(keys)-1], reason)
+
+		// Drop the intermediate maps emptied by this removal, innermost first
+		for i := len(parents) - 1; i > 0 && len(parents[i]) == 0; i-- {
+			changes.remove(parents[i-1], keys[i-1], reason)
+		}
+	}
+}
+
+// removeListDefaults applies removeDefaults to every map in the list stored at data[key].
+func removeListDefaults(data map[string]interface{}, key string, defaults []fieldDefault, changes *ChangeRecorder, reason string) {
+	items, ok := data[key].([]interface{})
+	if !ok {
+		return
+	}
+	for _, item := range items {
+		if itemMap, ok := item.(map[string]interface{}); ok {
+			removeDefaults(itemMap, defaults, changes, reason)
+		}
+	}
+}
+
+// shouldRemoveDefaults reports whether fields holding their server default are removed.
+// Defaults are runtime state, so they are kept when preserving runtime state.
+func shouldRemoveDefaults(options *CleanupOptions) bool {
+	return !options.KeepDefaults && !(options.PreserveResourceState && options.ResourceStateMode == "Runtime")
+}
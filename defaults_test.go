@@ -0,0 +1,183 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemoveDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    map[string]interface{}
+		defaults []fieldDefault
+		expected map[string]interface{}
+	}{
+		{
+			name: "removes a default Deployment strategy entirely",
+			input: map[string]interface{}{
+				"replicas":             2,
+				"revisionHistoryLimit": 10,
+				"strategy": map[string]interface{}{
+					"type":          "RollingUpdate",
+					"rollingUpdate": map[string]interface{}{"maxSurge": "25%", "maxUnavailable": "25%"},
+				},
+			},
+			defaults: kindSpecDefaults["Deployment"],
+			expected: map[string]interface{}{"replicas": 2},
+		},
+		{
+			name: "keeps non-default values",
+			input: map[string]interface{}{
+				"revisionHistoryLimit":    3,
+				"progressDeadlineSeconds": 600,
+				"strategy": map[string]interface{}{
+					"type":          "RollingUpdate",
+					"rollingUpdate": map[string]interface{}{"maxSurge": "50%", "maxUnavailable": "25%"},
+				},
+			},
+			defaults: kindSpecDefaults["Deployment"],
+			expected: map[string]interface{}{
+				"revisionHistoryLimit": 3,
+				"strategy": map[string]interface{}{
+					"rollingUpdate": map[string]interface{}{"maxSurge": "50%"},
+				},
+			},
+		},
+		{
+			name: "keeps a Recreate strategy",
+			input: map[string]interface{}{
+				"strategy": map[string]interface{}{"type": "Recreate"},
+			},
+			defaults: kindSpecDefaults["Deployment"],
+			expected: map[string]interface{}{
+				"strategy": map[string]interface{}{"type": "Recreate"},
+			},
+		},
+		{
+			name: "removes completions and parallelism of a single Pod Job",
+			input: map[string]interface{}{
+				"completions": 1,
+				"parallelism": 1,
+			},
+			defaults: kindSpecDefaults["Job"],
+			expected: map[string]interface{}{},
+		},
+		{
+			name: "keeps completions of a parallel Job",
+			input: map[string]interface{}{
+				"completions": 1,
+				"parallelism": 3,
+			},
+			defaults: kindSpecDefaults["Job"],
+			expected: map[string]interface{}{
+				"completions": 1,
+				"parallelism": 3,
+			},
+		},
+		{
+			name: "keeps parallelism of a Job with several completions",
+			input: map[string]interface{}{
+				"completions": 5,
+				"parallelism": 1,
+			},
+			defaults: kindSpecDefaults["Job"],
+			expected: map[string]interface{}{
+				"completions": 5,
+				"parallelism": 1,
+			},
+		},
+		{
+			name: "pod spec defaults",
+			input: map[string]interface{}{
+				"dnsPolicy":                     "None",
+				"terminationGracePeriodSeconds": 120,
+				"schedulerName":                 "default-scheduler",
+				"enableServiceLinks":            true,
+				"hostNetwork":                   true,
+				"restartPolicy":                 "Always",
+			},
+			defaults: podSpecDefaults,
+			expected: map[string]interface{}{
+				"dnsPolicy":                     "None",
+				"terminationGracePeriodSeconds": 120,
+				"hostNetwork":                   true,
+			},
+		},
+		{
+			name:     "Service targetPort equal to port",
+			input:    map[string]interface{}{"port": 80, "targetPort": 80, "protocol": "TCP"},
+			defaults: servicePortDefaults,
+			expected: map[string]interface{}{"port": 80},
+		},
+		{
+			name:     "Service named targetPort",
+			input:    map[string]interface{}{"port": 80, "targetPort": "http", "protocol": "UDP"},
+			defaults: servicePortDefaults,
+			expected: map[string]interface{}{"port": 80, "targetPort": "http", "protocol": "UDP"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			removeDefaults(tt.input, tt.defaults, nil, "")
+			if !reflect.DeepEqual(tt.expected, tt.input) {
+				t.Errorf("Unexpected result.\nExpected: %v\nActual: %v", tt.expected, tt.input)
+			}
+		})
+	}
+}
+
+func TestDefaultImagePullPolicy(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                            "Always",
+		"nginx:latest":                     "Always",
+		"nginx:1.25":                       "IfNotPresent",
+		"registry.example.com:5000/app":    "Always",
+		"registry.example.com:5000/app:v2": "IfNotPresent",
+		"nginx@sha256:0123456789abcdef":    "IfNotPresent",
+	}
+	for image, expected := range tests {
+		if actual := defaultImagePullPolicy(map[string]interface{}{"image": image}); actual != expected {
+			t.Errorf("Image %q: expected pull policy %q, got %v", image, expected, actual)
+		}
+	}
+}
+
+func TestCleanContainerSpecKeepsNonDefaults(t *testing.T) {
+	container := map[string]interface{}{
+		"name":                     "app",
+		"image":                    "nginx:1.25",
+		"imagePullPolicy":          "Always",
+		"terminationMessagePath":   "/dev/termination-log",
+		"terminationMessagePolicy": "FallbackToLogsOnError",
+		"stdin":                    true,
+		"readinessProbe": map[string]interface{}{
+			"httpGet":        map[string]interface{}{"path": "/healthz", "port": 8080, "scheme": "HTTP"},
+			"timeoutSeconds": 1,
+			"periodSeconds":  5,
+		},
+	}
+	expected := map[string]interface{}{
+		"name":                     "app",
+		"image":                    "nginx:1.25",
+		"imagePullPolicy":          "Always",
+		"terminationMessagePolicy": "FallbackToLogsOnError",
+		"stdin":                    true,
+		"readinessProbe": map[string]interface{}{
+			"httpGet":       map[string]interface{}{"path": "/healthz", "port": 8080},
+			"periodSeconds": 5,
+		},
+	}
+
+	cleanContainerSpec(container, &CleanupOptions{}, nil)
+	if !reflect.DeepEqual(expected, container) {
+		t.Errorf("Unexpected container.\nExpected: %v\nActual: %v", expected, container)
+	}
+
+	// With KeepDefaults nothing is removed
+	kept := map[string]interface{}{"name": "app", "image": "nginx", "imagePullPolicy": "Always"}
+	cleanContainerSpec(kept, &CleanupOptions{KeepDefaults: true}, nil)
+	if _, ok := kept["imagePullPolicy"]; !ok {
+		t.Errorf("Expected imagePullPolicy to be kept with KeepDefaults")
+	}
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Diff output formats for --diff-format.
+const (
+	diffFormatUnified    = "unified"    // Line diff of the YAML before and after cleaning
+	diffFormatStructured = "structured" // One line per removed field, with the reason it was removed
+)
+
+// diffContextLines is the number of unchanged lines shown around each change in unified diffs.
+const diffContextLines = 3
+
+// maxDiffEdits bounds the edit distance diffLines searches for. The Myers trace grows with the
+// square of the distance; documents that differ more are diffed as one replaced block.
+const maxDiffEdits = 1000
+
+// ANSI colours used for diffs written to a terminal.
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+)
+
+// simpleKeyPattern matches keys that can be written in dot notation in a field path.
+var simpleKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// appendKeyPath appends a map key to a field path, quoting keys such as annotation names.
+func appendKeyPath(path, key string) string {
+	if !simpleKeyPattern.MatchString(key) {
+		return path + "['" + key + "']"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// listElementName returns the name of a named list element (containers, volumes, env, ...).
+func listElementName(item interface{}) (string, bool) {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	name, ok := m["name"].(string)
+	return name, ok
+}
+
+// namedList reports whether every element of items has a unique name.
+func namedList(items []interface{}) bool {
+	seen := map[string]bool{}
+	for _, item := range items {
+		name, ok := listElementName(item)
+		if !ok || seen[name] {
+			return false
+		}
+		seen[name] = true
+	}
+	return len(items) > 0
+}
+
+// removedFields returns the fields present in before but missing from after, below path.
+// A removed map or list is reported once, not field by field. Elements of named lists are
+// matched by name, so removing one container does not shift the paths of the others.
+func removedFields(before, after interface{}, path string) []Change {
+	var removed []Change
+	switch b := before.(type) {
+	case map[string]interface{}:
+		a, ok := after.(map[string]interface{})
+		if !ok {
+			return nil // Replaced by a different type, not removed
+		}
+		for _, key := range sortedKeys(b) {
+			childPath := appendKeyPath(path, key)
+			if value, exists := a[key]; exists {
+				removed = append(removed, removedFields(b[key], value, childPath)...)
+			} else {
+				removed = append(removed, Change{Path: childPath, OldValue: b[key]})
+			}
+		}
+	case []interface{}:
+		a, ok := after.([]interface{})
+		if !ok {
+			return nil
+		}
+		switch {
+		case namedList(b) && (len(a) == 0 || namedList(a)):
+			byName := map[string]interface{}{}
+			for _, item := range a {
+				name, _ := listElementName(item)
+				byName[name] = item
+			}
+			for _, item := range b {
+				name, _ := listElementName(item)
+				childPath := fmt.Sprintf("%s[?(@.name==%q)]", path, name)
+				if value, exists := byName[name]; exists {
+					removed = append(removed, removedFields(item, value, childPath)...)
+				} else {
+					removed = append(removed, Change{Path: childPath, OldValue: item})
+				}
+			}
+		case len(a) == len(b):
+			for i := range b {
+				removed = append(removed, removedFields(b[i], a[i], fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		default:
+			// Match unchanged elements in order; the others were removed
+			next := 0
+			for i, item := range b {
+				if next < len(a) && reflect.DeepEqual(item, a[next]) {
+					next++
+					continue
+				}
+				removed = append(removed, Change{Path: fmt.Sprintf("%s[%d]", path, i), OldValue: item})
+			}
+		}
+	}
+	return removed
+}
+
+// sortedKeys returns the keys of m in a stable order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// diffOp is one line of a line diff: ' ' unchanged, '-' removed or '+' added.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines computes a shortest line diff between a and b using Myers' algorithm. When more
+// than maxDiffEdits lines differ, it returns the changed lines as one removed and added block.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	limit := n + m
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+
+	for d := 0; d <= min(limit, maxDiffEdits); d++ {
+		// Step d only reads the diagonals -d-1..d+1; keep just those
+		trace = append(trace, slices.Clone(v[offset-d-1:offset+d+2]))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // Move down: insertion
+			} else {
+				x = v[offset+k-1] + 1 // Move right: deletion
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackDiff(a, b, trace, d)
+			}
+		}
+	}
+	return replaceLines(a, b)
+}
+
+// replaceLines diffs a and b as their common first and last lines around one replaced block.
+func replaceLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	for _, line := range a[prefix : len(a)-suffix] {
+		ops = append(ops, diffOp{'-', line})
+	}
+	for _, line := range b[prefix : len(b)-suffix] {
+		ops = append(ops, diffOp{'+', line})
+	}
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// backtrackDiff walks the Myers trace back from the end and returns the edit script in order.
+// trace[d] holds the diagonals -d-1..d+1, so diagonal k is at index k+d+1.
+func backtrackDiff(a, b []string, trace [][]int, d int) []diffOp {
+	var ops []diffOp
+	x, y := len(a), len(b)
+	for ; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[k+d] < v[k+d+2]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+d+1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{' ', a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				ops = append(ops, diffOp{'+', b[y]})
+			} else {
+				x--
+				ops = append(ops, diffOp{'-', a[x]})
+			}
+		}
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// yamlLines encodes data as YAML and splits it into lines.
+func yamlLines(data interface{}) ([]string, error) {
+	if data == nil {
+		return nil, nil
+	}
+	encoded, err := yaml.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(string(encoded)), nil
+}
+
+// splitLines splits text into lines without their line breaks.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// objectLines returns the lines of the object before and after cleaning, as the output writer
+// encodes them. Reports of objects cleaned without a writer fall back to YAML of their fields.
+func objectLines(report *ObjectReport) (before, after []string, err error) {
+	if report.rendered {
+		return splitLines(report.before), splitLines(report.after), nil
+	}
+	if before, err = yamlLines(report.original); err != nil {
+		return nil, nil, err
+	}
+	after, err = yamlLines(report.cleaned)
+	return before, after, err
+}
+
+// diffWriter renders per-object diffs, optionally with ANSI colours.
+type diffWriter struct {
+	w     io.Writer
+	color bool
+}
+
+func (d *diffWriter) printf(color, format string, args ...interface{}) {
+	if d.color && color != "" {
+		fmt.Fprint(d.w, color)
+		defer fmt.Fprint(d.w, colorReset)
+	}
+	fmt.Fprintf(d.w, format, args...)
+}
+
+// writeUnified writes a unified diff between the object before and after cleaning.
+func (d *diffWriter) writeUnified(report *ObjectReport) error {
+	before, after, err := objectLines(report)
+	if err != nil {
+		return err
+	}
+	ops := diffLines(before, after)
+
+	d.printf(colorBold, "--- a/%s\n", report.key())
+	d.printf(colorBold, "+++ b/%s\n", report.key())
+
+	// Group the changes into hunks with diffContextLines of context
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		hunkStart := max(start-diffContextLines, 0)
+		hunkEnd, unchanged := start, 0
+		for i := start; i < len(ops) && unchanged <= 2*diffContextLines; i++ {
+			if ops[i].kind == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+				hunkEnd = i + 1
+			}
+		}
+		hunkEnd = min(hunkEnd+diffContextLines, len(ops))
+
+		oldLine, newLine := 1, 1
+		for _, op := range ops[:hunkStart] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, op := range ops[hunkStart:hunkEnd] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		d.printf(colorCyan, "@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount)
+		for _, op := range ops[hunkStart:hunkEnd] {
+			switch op.kind {
+			case '-':
+				d.printf(colorRed, "-%s\n", op.line)
+			case '+':
+				d.printf(colorGreen, "+%s\n", op.line)
+			default:
+				d.printf("", " %s\n", op.line)
+			}
+		}
+		start = hunkEnd
+	}
+	return nil
+}
+
+// writeStructured writes one line per removed field with its old value and the recorded reason.
+func (d *diffWriter) writeStructured(report *ObjectReport) error {
+	d.printf(colorBold, "%s\n", report.key())
+	for _, removed := range report.Removed {
+		value, err := json.Marshal(removed.OldValue)
+		if err != nil {
+			return err
+		}
+		path := removed.Path
+		if path == "" {
+			path = "<object>"
+		}
+		d.printf(colorRed, "  - %s: %s", path, truncate(string(value), 80))
+		d.printf(colorCyan, "  (%s)\n", removed.Reason)
+	}
+	return nil
+}
+
+// truncate shortens s to at most n characters, marking the cut with "...". It cuts between
+// runes, so multi-byte characters are kept whole.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+// writeDiff writes the diff of every object in report that cleaning changed.
+func writeDiff(w io.Writer, report *CleanupReport, format string, color bool) error {
+	d := &diffWriter{w: w, color: color}
+	for _, object := range report.Objects {
+		if !object.changed() {
+			continue
+		}
+		var err error
+		if format == diffFormatStructured {
+			err = d.writeStructured(object)
+		} else {
+			err = d.writeUnified(object)
+		}
+		if err != nil {
+			return fmt.Errorf("error writing diff for %s: %w", object.key(), err)
+		}
+	}
+	return nil
+}
+
+// writeJSONReport writes report as indented JSON.
+func writeJSONReport(w io.Writer, report *CleanupReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRemovedFields(t *testing.T) {
+	before := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":        "web",
+			"annotations": map[string]interface{}{"example.com/build": "42", "team": "shop"},
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "sidecar", "image": "proxy"},
+				map[string]interface{}{"name": "app", "image": "nginx", "stdin": false},
+			},
+			"args": []interface{}{"--a", "--b", "--c"},
+		},
+	}
+	after := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":        "web",
+			"annotations": map[string]interface{}{"team": "shop"},
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "app", "image": "nginx"},
+			},
+			"args": []interface{}{"--a", "--c"},
+		},
+	}
+	expected := []Change{
+		{Path: "metadata.annotations['example.com/build']", OldValue: "42"},
+		{Path: "spec.args[1]", OldValue: "--b"},
+		{Path: `spec.containers[?(@.name=="sidecar")]`, OldValue: map[string]interface{}{"name": "sidecar", "image": "proxy"}},
+		{Path: `spec.containers[?(@.name=="app")].stdin`, OldValue: false},
+	}
+
+	if actual := removedFields(before, after, ""); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Unexpected removed fields.\nExpected: %v\nActual: %v", expected, actual)
+	}
+	// Reported paths are valid field rule paths
+	for _, removed := range expected {
+		if _, err := parseFieldPath(removed.Path); err != nil {
+			t.Errorf("Path %q is not a valid field path: %v", removed.Path, err)
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	before := []string{"a", "b", "c", "d"}
+	after := []string{"a", "c", "d", "e"}
+	expected := []diffOp{{' ', "a"}, {'-', "b"}, {' ', "c"}, {' ', "d"}, {'+', "e"}}
+	if actual := diffLines(before, after); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Unexpected diff.\nExpected: %v\nActual: %v", expected, actual)
+	}
+	if actual := diffLines(nil, nil); len(actual) != 0 {
+		t.Errorf("Expected an empty diff for empty inputs, got %v", actual)
+	}
+
+	// Beyond maxDiffEdits the changed lines are diffed as one block
+	before, after = []string{"start"}, []string{"start"}
+	for i := 0; i <= maxDiffEdits; i++ {
+		before = append(before, fmt.Sprintf("old %d", i))
+		after = append(after, fmt.Sprintf("new %d", i))
+	}
+	before, after = append(before, "end"), append(after, "end")
+	ops := diffLines(before, after)
+	if len(ops) != 2*maxDiffEdits+4 || ops[0] != (diffOp{' ', "start"}) || ops[1] != (diffOp{'-', "old 0"}) ||
+		ops[maxDiffEdits+2] != (diffOp{'+', "new 0"}) || ops[len(ops)-1] != (diffOp{' ', "end"}) {
+		t.Errorf("Unexpected diff of %d lines starting with %v", len(ops), ops[:2])
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := map[string]string{
+		"short":                  "short",
+		`"0123456789abcdefghij"`: `"0123456...`,
+		`"äöüäöüäöüäöüäöü"`:      `"äöüäöüä...`,
+	}
+	for input, expected := range tests {
+		if actual := truncate(input, 11); actual != expected {
+			t.Errorf("Unexpected truncation of %s.\nExpected: %s\nActual: %s", input, expected, actual)
+		}
+	}
+}
+
+func TestWriteDiffUsesWriterOutput(t *testing.T) {
+	input := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  # Read by the shop
+  name: settings
+  uid: 0c5e1d2a
+data:
+  zeta: "1"
+  alpha: "2"
+`
+	report := &CleanupReport{}
+	var output, diff bytes.Buffer
+	if err := cleanupManifest(strings.NewReader(input), &output, &CleanupOptions{Report: report}); err != nil {
+		t.Fatalf("cleanupManifest returned error: %v", err)
+	}
+	if err := writeDiff(&diff, report, diffFormatUnified, false); err != nil {
+		t.Fatalf("writeDiff returned error: %v", err)
+	}
+
+	// Comments and key order are diffed as they are written
+	expected := `--- a/v1/ConfigMap/settings
++++ b/v1/ConfigMap/settings
+@@ -3,7 +3,6 @@
+ metadata:
+   # Read by the shop
+   name: settings
+-  uid: 0c5e1d2a
+ data:
+   zeta: "1"
+   alpha: "2"
+`
+	if diff.String() != expected {
+		t.Errorf("Unexpected diff.\nExpected: %s\nActual: %s", expected, diff.String())
+	}
+}
+
+func TestReportAttributesRules(t *testing.T) {
+	input := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  labels:
+    owner: platform
+data:
+  key: value
+`
+	report := &CleanupReport{}
+	options := &CleanupOptions{
+		Report:     report,
+		FieldRules: []FieldRule{{Path: "metadata.labels.owner", Action: ruleActionRemove}},
+	}
+	var output bytes.Buffer
+	if err := cleanupManifest(strings.NewReader(input), &output, options); err != nil {
+		t.Fatalf("cleanupManifest returned error: %v", err)
+	}
+
+	if len(report.Objects) != 1 {
+		t.Fatalf("Expected 1 object in the report, got %d", len(report.Objects))
+	}
+	expected := []Change{{Path: "metadata.labels.owner", OldValue: "platform", Reason: "rule:metadata.labels.owner"}}
+	if actual := report.Objects[0].Removed; !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Unexpected removed fields.\nExpected: %v\nActual: %v", expected, actual)
+	}
+}
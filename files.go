@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// manifestExtensions are the file extensions read when walking a directory with -R.
+var manifestExtensions = []string{".yaml", ".yml", ".json"}
+
+// inputFile is a file to clean. dir is the directory argument it was found in, or empty for files
+// given directly; it determines the path of the file in a mirrored output directory.
+type inputFile struct {
+	path string
+	dir  string
+}
+
+// collectInputs expands the input arguments into the files to clean. Directories are walked when
+// recursive is set; hidden files and directories (.git, .kleanup.yaml, ...) are skipped.
+func collectInputs(args []string, recursive bool) ([]inputFile, error) {
+	var files []inputFile
+	for _, arg := range args {
+		if arg == "-" {
+			files = append(files, inputFile{path: arg})
+			continue
+		}
+		info, err := os.Stat(arg)
+		if err != nil || !info.IsDir() {
+			files = append(files, inputFile{path: arg}) // Missing files are reported when they are read
+			continue
+		}
+		if !recursive {
+			return nil, fmt.Errorf("'%s' is a directory (use -R to clean the manifests below it)", arg)
+		}
+		err = filepath.WalkDir(arg, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if path != arg && strings.HasPrefix(entry.Name(), ".") {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !entry.IsDir() && slices.Contains(manifestExtensions, strings.ToLower(filepath.Ext(path))) {
+				files = append(files, inputFile{path: path, dir: arg})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error walking '%s': %w", arg, err)
+		}
+	}
+	return files, nil
+}
+
+// mirrorPath returns the path of input below outputDir, keeping its path relative to the
+// directory it was found in.
+func mirrorPath(outputDir string, input inputFile) (string, error) {
+	if input.dir == "" {
+		return filepath.Join(outputDir, filepath.Base(input.path)), nil
+	}
+	rel, err := filepath.Rel(input.dir, input.path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(outputDir, rel), nil
+}
+
+// writeMirroredFile writes data to path, creating its parent directories.
+func writeMirroredFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating directory for '%s': %w", path, err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing output file '%s': %w", path, err)
+	}
+	log.Printf("Wrote output to file: %s", path)
+	return nil
+}
+
+// rewriteFile replaces the content of path with data, keeping its permissions. Unchanged files are
+// not touched, and neither are files without any document to write (e.g. only comments). With
+// backup the original content is kept in path.bak. The new content is written to a temporary file
+// first, so an interrupted run never leaves a truncated manifest behind.
+func rewriteFile(path string, data []byte, backup bool) error {
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading '%s': %w", path, err)
+	}
+	if bytes.Equal(original, data) {
+		log.Printf("Unchanged: %s", path)
+		return nil
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		log.Printf("Warning: not rewriting '%s': no Kubernetes objects to write", path)
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("error reading '%s': %w", path, err)
+	}
+	if backup {
+		if err := os.WriteFile(path+".bak", original, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("error writing backup of '%s': %w", path, err)
+		}
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("error rewriting '%s': %w", path, err)
+	}
+	defer os.Remove(temp.Name()) // No-op once renamed
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("error rewriting '%s': %w", path, err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("error rewriting '%s': %w", path, err)
+	}
+	if err := os.Chmod(temp.Name(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("error rewriting '%s': %w", path, err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("error rewriting '%s': %w", path, err)
+	}
+	log.Printf("Rewrote file: %s", path)
+	return nil
+}
+
+// fileOptions returns the options to clean input with when every file gets its own output:
+// documents that are not Kubernetes objects are kept, and JSON files stay JSON unless a JSON format
+// was chosen explicitly.
+func fileOptions(options *CleanupOptions, input inputFile) *CleanupOptions {
+	resolved := *options
+	resolved.KeepUnknown = true
+	if options.OutputFormat == outputFormatYAML && strings.ToLower(filepath.Ext(input.path)) == ".json" {
+		resolved.OutputFormat = outputFormatJSON
+	}
+	return &resolved
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"slices"
+
+	yamlv3 "go.yaml.in/yaml/v3"
+)
+
+// Key orders for CleanupOptions.KeyOrder.
+const (
+	keyOrderCanonical = "canonical" // kubectl/kustomize-like order, see canonicalKeys
+	keyOrderPreserve  = "preserve"  // Keep the order of the input; new keys are appended
+)
+
+// canonicalKeys lists the keys that come first in a map, by context. Other keys keep their
+// relative order after them; status always comes last.
+var canonicalKeys = map[string][]string{
+	"object":    {"apiVersion", "kind", "metadata", "type", "spec", "data", "stringData", "binaryData", "immutable"},
+	"metadata":  {"name", "generateName", "namespace", "labels", "annotations"},
+	"spec":      {"replicas", "selector", "serviceName", "template", "strategy", "updateStrategy"},
+	"template":  {"metadata", "spec"},
+	"container": {"name", "image", "imagePullPolicy", "command", "args", "workingDir", "env", "envFrom", "ports", "resources", "volumeMounts", "volumeDevices", "livenessProbe", "readinessProbe", "startupProbe", "lifecycle", "securityContext"},
+	"port":      {"name", "containerPort", "port", "targetPort", "nodePort", "protocol"},
+	"named":     {"name"}, // Elements of other named lists (env, volumes, ...)
+}
+
+// containerListKeys are the pod spec keys holding containers.
+var containerListKeys = []string{"containers", "initContainers", "ephemeralContainers"}
+
+// orderKeys sorts the mapping nodes below node into canonical order. key is the key node was
+// stored under in its parent, and element reports whether node is an element of a list under key.
+func orderKeys(node *yamlv3.Node, key string, element bool) {
+	switch node.Kind {
+	case yamlv3.DocumentNode:
+		for _, child := range node.Content {
+			orderKeys(child, "", false)
+		}
+	case yamlv3.SequenceNode:
+		for _, child := range node.Content {
+			orderKeys(child, key, true)
+		}
+	case yamlv3.MappingNode:
+		context := keyContext(node, key, element)
+		sortMapping(node, canonicalKeys[context], context == "object")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childKey := node.Content[i].Value
+			if context == "object" && childKey == "items" {
+				childKey = "" // List items are objects themselves
+			}
+			orderKeys(node.Content[i+1], childKey, false)
+		}
+	}
+}
+
+// keyContext returns the canonicalKeys entry for a mapping stored under key.
+func keyContext(node *yamlv3.Node, key string, element bool) string {
+	switch {
+	case key == "":
+		return "object" // A document, or an element of a List's items
+	case element && slices.Contains(containerListKeys, key):
+		return "container"
+	case element && key == "ports":
+		return "port"
+	case element && mappingValue(node, "name") != nil:
+		return "named"
+	case !element && key == "spec" && mappingValue(node, "template") != nil:
+		return "spec" // Workload specs; other specs keep their order
+	case !element && (key == "metadata" || key == "template"):
+		return key
+	}
+	return ""
+}
+
+// sortMapping moves the keys listed in first to the front of a mapping node, in that order,
+// keeping the relative order of the other keys. Objects (object is true) also get status moved
+// to the end and keep the comment heading the document at the top.
+func sortMapping(node *yamlv3.Node, first []string, object bool) {
+	if len(first) == 0 && !object {
+		return
+	}
+	rank := func(key string) int {
+		if i := slices.Index(first, key); i >= 0 {
+			return i
+		}
+		if object && key == "status" {
+			return len(first) + 1
+		}
+		return len(first)
+	}
+
+	pairs := make([][2]*yamlv3.Node, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, [2]*yamlv3.Node{node.Content[i], node.Content[i+1]})
+	}
+	slices.SortStableFunc(pairs, func(a, b [2]*yamlv3.Node) int {
+		return rank(a[0].Value) - rank(b[0].Value)
+	})
+	content := make([]*yamlv3.Node, 0, len(node.Content))
+	for _, pair := range pairs {
+		content = append(content, pair[0], pair[1])
+	}
+	if object && len(content) > 0 && content[0] != node.Content[0] && node.Content[0].HeadComment != "" {
+		header := node.Content[0].HeadComment
+		node.Content[0].HeadComment = ""
+		content[0].HeadComment = joinComments(header, content[0].HeadComment)
+	}
+	node.Content = content
+}
+
+// joinComments joins two comment blocks, either of which may be empty.
+func joinComments(first, second string) string {
+	if first == "" || second == "" {
+		return first + second
+	}
+	return first + "\n" + second
+}
@@ -2,7 +2,10 @@ module github.com/OpScaleHub/Kleanup
 
 go 1.23
 
-require gopkg.in/yaml.v2 v2.4.0
+require (
+	go.yaml.in/yaml/v3 v3.0.5
+	gopkg.in/yaml.v2 v2.4.0
+)
 
 require (
 	github.com/kr/pretty v0.3.1 // indirect
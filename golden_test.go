@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata/golden")
+
+// TestGoldenFiles cleans real `kubectl get -o yaml` dumps from testdata/golden with the default
+// options and compares the result with the matching .golden.yaml file.
+// Run `go test -run TestGoldenFiles -update` to regenerate the expected output.
+func TestGoldenFiles(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "golden", "*.yaml"))
+	if err != nil {
+		t.Fatalf("failed to list golden inputs: %v", err)
+	}
+
+	for _, inputPath := range inputs {
+		if strings.HasSuffix(inputPath, ".golden.yaml") {
+			continue
+		}
+		goldenPath := strings.TrimSuffix(inputPath, ".yaml") + ".golden.yaml"
+
+		t.Run(filepath.Base(inputPath), func(t *testing.T) {
+			input, err := os.ReadFile(inputPath)
+			if err != nil {
+				t.Fatalf("failed to read input: %v", err)
+			}
+
+			var out bytes.Buffer
+			if err := cleanupManifest(bytes.NewReader(input), &out, defaultCleanupOptions()); err != nil {
+				t.Fatalf("cleanupManifest returned error: %v", err)
+			}
+
+			if *updateGolden {
+				if err := os.WriteFile(goldenPath, out.Bytes(), 0o644); err != nil {
+					t.Fatalf("failed to update golden file: %v", err)
+				}
+			}
+
+			expected, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(expected, out.Bytes()) {
+				t.Errorf("Output does not match %s.\nExpected:\n%s\nActual:\n%s", goldenPath, expected, out.String())
+			}
+
+			// These runtime artefacts sit below the top-level maps and must never survive cleanup
+			for _, leftover := range []string{
+				"kubectl.kubernetes.io/",
+				"deployment.kubernetes.io/",
+				"creationTimestamp",
+				"terminationMessagePath",
+				"imagePullPolicy",
+				"kube-api-access-",
+				"serviceAccountToken",
+			} {
+				if strings.Contains(out.String(), leftover) {
+					t.Errorf("Expected %q to be removed from the output", leftover)
+				}
+			}
+		})
+	}
+}
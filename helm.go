@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	yamlv3 "go.yaml.in/yaml/v3"
+)
+
+// helmTemplateName names the template files of a chart written by klean helmify.
+const helmTemplateName = "{{.Kind | lower}}-{{.Name}}.yaml"
+
+// chartMarkerPattern matches the placeholder scalars standing in for template expressions.
+var chartMarkerPattern = regexp.MustCompile(`__klean_helm_(\d+)__`)
+
+// templateDelimiters escapes the template delimiters in the text of a template, so values like
+// annotations holding "{{ }}" are rendered as they are.
+var templateDelimiters = strings.NewReplacer("{{", `{{ "{{" }}`, "}}", `{{ "}}" }}`)
+
+// Fields referencing other objects by name, templated with .Release.Name like the names of the
+// objects they reference. chartNameKeys hold a name wherever they appear; chartNameParents are
+// the fields (or lists) whose name key holds one, e.g. configMapRef.name or subjects[].name.
+var (
+	chartNameKeys    = []string{"serviceAccountName", "serviceName", "secretName", "claimName", "volumeName", "ingressClassName", "storageClassName", "priorityClassName"}
+	chartNameParents = []string{"metadata", "configMap", "secret", "configMapRef", "secretRef", "configMapKeyRef", "secretKeyRef", "service", "scaleTargetRef", "roleRef", "imagePullSecrets", "secrets", "subjects", "parentRefs", "backendRefs"}
+)
+
+// ChartWriter collects the cleaned objects of a run and writes them as a Helm chart: Chart.yaml,
+// a values.yaml with the settings usually changed per release, and one template per object.
+//
+// Objects are only written by Close, once every object name is known: names starting with the
+// chart name are templated with .Release.Name wherever they are referenced, so installing the
+// chart under its own name reproduces the input.
+type ChartWriter struct {
+	Dir  string
+	Name string
+
+	templates *SplitWriter
+	objects   []chartObject
+	markers   []chartMarker
+}
+
+type chartObject struct {
+	obj  KubernetesObject
+	node *yamlv3.Node
+}
+
+// chartMarker is a template expression. The node it replaces is encoded as a placeholder scalar,
+// which is swapped for the expression once the template has been encoded.
+type chartMarker struct {
+	expr  string
+	block bool // expr yields a YAML block rendered below the key (toYaml ... | nindent)
+}
+
+// chartMetadata is the Chart.yaml of a chart.
+type chartMetadata struct {
+	APIVersion  string `yaml:"apiVersion"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Type        string `yaml:"type"`
+	Version     string `yaml:"version"`
+}
+
+// NewChartWriter returns a ChartWriter writing the chart name to dir. An empty name uses the name
+// of dir.
+func NewChartWriter(dir, name string) (*ChartWriter, error) {
+	if name == "" {
+		name = filepath.Base(filepath.Clean(dir))
+	}
+	templates, err := NewSplitWriter(filepath.Join(dir, "templates"), helmTemplateName)
+	if err != nil {
+		return nil, err
+	}
+	return &ChartWriter{Dir: dir, Name: name, templates: templates}, nil
+}
+
+// add queues obj, encoded as node, for the chart.
+func (c *ChartWriter) add(obj *KubernetesObject, node *yamlv3.Node) {
+	c.objects = append(c.objects, chartObject{obj: *obj, node: node})
+}
+
+// Close writes the chart.
+func (c *ChartWriter) Close() error {
+	names := c.releaseNames()
+	var namespaces []string
+	for _, object := range c.objects {
+		if object.obj.namespace != "" && !slices.Contains(namespaces, object.obj.namespace) {
+			namespaces = append(namespaces, object.obj.namespace)
+		}
+	}
+
+	values := &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map", HeadComment: fmt.Sprintf("Default values for %s, extracted by klean helmify.", c.Name)}
+	usedKeys := map[string]bool{}
+	for _, object := range c.objects {
+		key := c.valuesKey(&object.obj)
+		for i := 2; usedKeys[key]; i++ {
+			key = fmt.Sprintf("%s%d", c.valuesKey(&object.obj), i)
+		}
+		usedKeys[key] = true
+
+		c.templateNamespaces(&object, namespaces)
+		if section := c.parameterize(&object, key); len(section.Content) > 0 {
+			values.Content = append(values.Content, scalarNode(key), section)
+		}
+		c.templateNames(object.node, "", names)
+
+		data, err := c.encodeTemplate(object.node)
+		if err != nil {
+			return fmt.Errorf("error encoding template for %s %s: %w", object.obj.Kind, object.obj.Metadata["name"], err)
+		}
+		if err := c.templates.write(&object.obj, data); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := (&documentWriter{w: &buf}).write(values, defaultIndent, defaultCompactSeq); err != nil {
+		return err
+	}
+	if err := writeMirroredFile(filepath.Join(c.Dir, "values.yaml"), buf.Bytes()); err != nil {
+		return err
+	}
+
+	chart, err := yamlv3.Marshal(chartMetadata{
+		APIVersion:  "v2",
+		Name:        c.Name,
+		Description: "Generated by klean helmify from cleaned manifests",
+		Type:        "application",
+		Version:     "0.1.0",
+	})
+	if err != nil {
+		return err
+	}
+	return writeMirroredFile(filepath.Join(c.Dir, "Chart.yaml"), chart)
+}
+
+// releaseNames returns the template expressions for the object names derived from the chart
+// name: the chart name itself and names starting with "<chart name>-".
+func (c *ChartWriter) releaseNames() map[string]string {
+	names := map[string]string{}
+	for _, object := range c.objects {
+		name, _ := object.obj.Metadata["name"].(string)
+		switch {
+		case name == c.Name:
+			names[name] = "{{ .Release.Name }}"
+		case strings.HasPrefix(name, c.Name+"-"):
+			names[name] = "{{ .Release.Name }}-" + strings.TrimPrefix(name, c.Name+"-")
+		}
+	}
+	return names
+}
+
+// marker returns a placeholder node for expr.
+func (c *ChartWriter) marker(expr string, block bool) *yamlv3.Node {
+	c.markers = append(c.markers, chartMarker{expr: expr, block: block})
+	return scalarNode(fmt.Sprintf("__klean_helm_%d__", len(c.markers)-1))
+}
+
+// templateNamespaces replaces the namespace of a namespaced object, and the namespaces of binding
+// subjects that belong to the chart, with .Release.Namespace.
+func (c *ChartWriter) templateNamespaces(object *chartObject, namespaces []string) {
+	metadata := mappingValue(object.node, "metadata")
+	if object.obj.namespace != "" && metadata != nil {
+		setMappingValue(metadata, "namespace", c.marker("{{ .Release.Namespace }}", false), "name")
+	}
+	if subjects := mappingValue(object.node, "subjects"); subjects != nil && subjects.Kind == yamlv3.SequenceNode {
+		for _, subject := range subjects.Content {
+			if namespace := mappingValue(subject, "namespace"); namespace != nil && slices.Contains(namespaces, namespace.Value) {
+				setMappingValue(subject, "namespace", c.marker("{{ .Release.Namespace }}", false), "")
+			}
+		}
+	}
+}
+
+// parameterize moves the settings usually changed per release into a values section stored under
+// key: replicas, container images and resources, Service ports and ConfigMap data.
+func (c *ChartWriter) parameterize(object *chartObject, key string) *yamlv3.Node {
+	section := &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+	path := ".Values." + key
+	spec := mappingValue(object.node, "spec")
+
+	var podSpec *yamlv3.Node
+	switch object.obj.Kind {
+	case "Deployment", "StatefulSet", "ReplicaSet":
+		if replicas := mappingValue(spec, "replicas"); replicas != nil && replicas.Kind == yamlv3.ScalarNode {
+			section.Content = append(section.Content, scalarNode("replicas"), replicas)
+			setMappingValue(spec, "replicas", c.marker("{{ "+path+".replicas }}", false), "")
+		}
+		podSpec = mappingValue(mappingValue(spec, "template"), "spec")
+	case "DaemonSet", "Job":
+		podSpec = mappingValue(mappingValue(spec, "template"), "spec")
+	case "CronJob":
+		podSpec = mappingValue(mappingValue(mappingValue(mappingValue(spec, "jobTemplate"), "spec"), "template"), "spec")
+	case "Pod":
+		podSpec = spec
+	case "Service":
+		if ports := mappingValue(spec, "ports"); ports != nil && ports.Kind == yamlv3.SequenceNode && len(ports.Content) > 0 {
+			section.Content = append(section.Content, scalarNode("ports"), ports)
+			setMappingValue(spec, "ports", c.marker("toYaml "+path+".ports", true), "")
+		}
+	case "ConfigMap":
+		if data := mappingValue(object.node, "data"); data != nil && data.Kind == yamlv3.MappingNode && len(data.Content) > 0 {
+			section.Content = append(section.Content, scalarNode("data"), data)
+			setMappingValue(object.node, "data", c.marker("toYaml "+path+".data", true), "")
+		}
+	}
+
+	for _, listKey := range []string{"initContainers", "containers"} {
+		containers := mappingValue(podSpec, listKey)
+		if containers == nil || containers.Kind != yamlv3.SequenceNode {
+			continue
+		}
+		list := &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+		for _, container := range containers.Content {
+			name := mappingValue(container, "name")
+			if name == nil {
+				continue
+			}
+			containerKey := lowerCamel(name.Value)
+			containerPath := path + "." + listKey + "." + containerKey
+			if values := c.parameterizeContainer(container, containerPath); len(values.Content) > 0 {
+				list.Content = append(list.Content, scalarNode(containerKey), values)
+			}
+		}
+		if len(list.Content) > 0 {
+			section.Content = append(section.Content, scalarNode(listKey), list)
+		}
+	}
+	return section
+}
+
+// parameterizeContainer moves the image and resources of a container into values stored at path.
+func (c *ChartWriter) parameterizeContainer(container *yamlv3.Node, path string) *yamlv3.Node {
+	values := &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+	if image := mappingValue(container, "image"); image != nil && image.Kind == yamlv3.ScalarNode {
+		repository, tag := splitImage(image.Value)
+		imageValues := &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+		imageValues.Content = append(imageValues.Content, scalarNode("repository"), scalarNode(repository))
+		expr := `"{{ ` + path + `.image.repository }}`
+		if tag != "" {
+			tagNode := scalarNode(tag)
+			tagNode.Style = yamlv3.DoubleQuotedStyle // Tags like 1.25 must stay strings
+			imageValues.Content = append(imageValues.Content, scalarNode("tag"), tagNode)
+			expr += `:{{ ` + path + `.image.tag }}`
+		}
+		values.Content = append(values.Content, scalarNode("image"), imageValues)
+		setMappingValue(container, "image", c.marker(expr+`"`, false), "")
+	}
+	if resources := mappingValue(container, "resources"); resources != nil && resources.Kind == yamlv3.MappingNode && len(resources.Content) > 0 {
+		values.Content = append(values.Content, scalarNode("resources"), resources)
+		setMappingValue(container, "resources", c.marker("toYaml "+path+".resources", true), "")
+	}
+	return values
+}
+
+// templateNames replaces the names and name references (see chartNameKeys) below node that name
+// an object of the chart with their .Release.Name expression. key is the key node is stored under;
+// list elements are visited with the key of their list.
+func (c *ChartWriter) templateNames(node *yamlv3.Node, key string, names map[string]string) {
+	switch node.Kind {
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childKey := node.Content[i].Value
+			if slices.Contains(chartNameKeys, childKey) || (childKey == "name" && slices.Contains(chartNameParents, key)) {
+				if expr, ok := c.nameExpr(node.Content[i+1], names); ok {
+					node.Content[i+1] = c.marker(expr, false)
+				}
+				continue
+			}
+			c.templateNames(node.Content[i+1], childKey, names)
+		}
+	case yamlv3.SequenceNode:
+		for _, child := range node.Content {
+			c.templateNames(child, key, names)
+		}
+	}
+}
+
+// nameExpr returns the .Release.Name expression for a string scalar naming an object of the chart.
+func (c *ChartWriter) nameExpr(node *yamlv3.Node, names map[string]string) (string, bool) {
+	if node.Kind != yamlv3.ScalarNode || node.Tag != "!!str" {
+		return "", false
+	}
+	expr, ok := names[node.Value]
+	return expr, ok
+}
+
+// encodeTemplate encodes node, escapes the template delimiters of its content and swaps the
+// placeholders for their template expressions.
+func (c *ChartWriter) encodeTemplate(node *yamlv3.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := (&documentWriter{w: &buf}).write(node, defaultIndent, defaultCompactSeq); err != nil {
+		return nil, err
+	}
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(templateDelimiters.Replace(buf.String()), "\n") {
+		out.WriteString(chartMarkerPattern.ReplaceAllStringFunc(line, func(placeholder string) string {
+			i, _ := strconv.Atoi(chartMarkerPattern.FindStringSubmatch(placeholder)[1])
+			marker := c.markers[i]
+			if !marker.block {
+				return marker.expr
+			}
+			return fmt.Sprintf("{{- %s | nindent %d }}", marker.expr, keyColumn(line)+defaultIndent)
+		}))
+	}
+	return []byte(out.String()), nil
+}
+
+// keyColumn returns the column of the mapping key on an encoded line, after any "- " list markers.
+func keyColumn(line string) int {
+	column := len(line) - len(strings.TrimLeft(line, " "))
+	for strings.HasPrefix(line[column:], "- ") {
+		column += 2
+		column += len(line[column:]) - len(strings.TrimLeft(line[column:], " "))
+	}
+	return column
+}
+
+// setMappingValue sets the value of key in a mapping node. A new key is inserted after the key
+// named after, or at the end.
+func setMappingValue(node *yamlv3.Node, key string, value *yamlv3.Node, after string) {
+	insert := len(node.Content)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		switch node.Content[i].Value {
+		case key:
+			node.Content[i+1] = value
+			return
+		case after:
+			insert = i + 2
+		}
+	}
+	node.Content = slices.Insert(node.Content, insert, scalarNode(key), value)
+}
+
+// scalarNode returns a plain string scalar.
+func scalarNode(value string) *yamlv3.Node {
+	return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// splitImage splits a container image into its repository and tag. Images pinned by digest are
+// kept whole as the repository.
+func splitImage(image string) (repository, tag string) {
+	if strings.Contains(image, "@") {
+		return image, ""
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return image, "" // No tag, or the colon belongs to a registry port
+	}
+	return image[:i], image[i+1:]
+}
+
+// valuesKey returns the values.yaml key of obj: its name without the chart name prefix and its
+// kind in lower camel case, e.g. webDeployment for the Deployment "shop-web" of the chart "shop".
+func (c *ChartWriter) valuesKey(obj *KubernetesObject) string {
+	name, _ := obj.Metadata["name"].(string)
+	if name == c.Name {
+		name = ""
+	}
+	return lowerCamel(strings.TrimPrefix(name, c.Name+"-") + "-" + obj.Kind)
+}
+
+// lowerCamel turns a Kubernetes name into an identifier usable in a .Values path, e.g. my-app.v2
+// becomes myAppV2.
+func lowerCamel(name string) string {
+	var out strings.Builder
+	upper := false
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = out.Len() > 0
+			continue
+		}
+		switch {
+		case out.Len() == 0 && unicode.IsDigit(r):
+			out.WriteRune('_')
+			out.WriteRune(r)
+		case out.Len() == 0:
+			out.WriteRune(unicode.ToLower(r))
+		case upper:
+			out.WriteRune(unicode.ToUpper(r))
+		default:
+			out.WriteRune(r)
+		}
+		upper = false
+	}
+	if out.Len() == 0 {
+		return "_"
+	}
+	return out.String()
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChartWriter(t *testing.T) {
+	input := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: shop-web
+  namespace: shop
+  annotations:
+    example.com/greeting: Hello {{ .Name }}
+spec:
+  replicas: 3
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.25
+        env:
+        - name: UPSTREAM
+          value: shop-settings
+        envFrom:
+        - configMapRef:
+            name: shop-settings
+        resources:
+          limits:
+            cpu: 500m
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: shop-settings
+  namespace: shop
+data:
+  mode: production
+`
+	dir := filepath.Join(t.TempDir(), "shop")
+	chart, err := NewChartWriter(dir, "")
+	if err != nil {
+		t.Fatalf("NewChartWriter returned error: %v", err)
+	}
+	options := defaultCleanupOptions()
+	options.Chart = chart
+	var output bytes.Buffer
+	if err := cleanupManifest(strings.NewReader(input), &output, options); err != nil {
+		t.Fatalf("cleanupManifest returned error: %v", err)
+	}
+	if output.Len() != 0 {
+		t.Errorf("Expected no output besides the chart, got:\n%s", output.String())
+	}
+	if err := chart.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	expected := map[string]string{
+		"Chart.yaml": "apiVersion: v2\nname: shop\ndescription: Generated by klean helmify from cleaned manifests\ntype: application\nversion: 0.1.0\n",
+		"templates/deployment-shop-web.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}-web
+  namespace: {{ .Release.Namespace }}
+  annotations:
+    example.com/greeting: Hello {{ "{{" }} .Name {{ "}}" }}
+spec:
+  replicas: {{ .Values.webDeployment.replicas }}
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: "{{ .Values.webDeployment.containers.web.image.repository }}:{{ .Values.webDeployment.containers.web.image.tag }}"
+        env:
+        - name: UPSTREAM
+          value: shop-settings
+        envFrom:
+        - configMapRef:
+            name: {{ .Release.Name }}-settings
+        resources: {{- toYaml .Values.webDeployment.containers.web.resources | nindent 10 }}
+`,
+		"templates/configmap-shop-settings.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}-settings
+  namespace: {{ .Release.Namespace }}
+data: {{- toYaml .Values.settingsConfigMap.data | nindent 2 }}
+`,
+		"values.yaml": `# Default values for shop, extracted by klean helmify.
+webDeployment:
+  replicas: 3
+  containers:
+    web:
+      image:
+        repository: nginx
+        tag: "1.25"
+      resources:
+        limits:
+          cpu: 500m
+settingsConfigMap:
+  data:
+    mode: production
+`,
+	}
+	for path, content := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Errorf("Expected file %s: %v", path, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("Unexpected content of %s.\nExpected:\n%s\nActual:\n%s", path, content, data)
+		}
+	}
+}
+
+func TestSplitImage(t *testing.T) {
+	tests := []struct {
+		image              string
+		expectedRepository string
+		expectedTag        string
+	}{
+		{image: "nginx", expectedRepository: "nginx"},
+		{image: "nginx:1.25", expectedRepository: "nginx", expectedTag: "1.25"},
+		{image: "registry.local:5000/shop/web", expectedRepository: "registry.local:5000/shop/web"},
+		{image: "registry.local:5000/shop/web:v2", expectedRepository: "registry.local:5000/shop/web", expectedTag: "v2"},
+		{image: "nginx@sha256:0123abcd", expectedRepository: "nginx@sha256:0123abcd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			repository, tag := splitImage(tt.image)
+			if repository != tt.expectedRepository || tag != tt.expectedTag {
+				t.Errorf("Unexpected split.\nExpected: %s %s\nActual: %s %s", tt.expectedRepository, tt.expectedTag, repository, tag)
+			}
+		})
+	}
+}
+
+func TestLowerCamel(t *testing.T) {
+	tests := map[string]string{
+		"web":         "web",
+		"my-app.v2":   "myAppV2",
+		"Web-Service": "webService",
+		"1st-replica": "_1stReplica",
+		"--":          "_",
+	}
+	for input, expected := range tests {
+		if actual := lowerCamel(input); actual != expected {
+			t.Errorf("Unexpected identifier for %q.\nExpected: %s\nActual: %s", input, expected, actual)
+		}
+	}
+}
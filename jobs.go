@@ -0,0 +1,93 @@
+package main
+
+// jobStatusRuntimeFields are the status fields of Jobs and CronJobs that describe the runs in
+// progress. They are removed even when the status is kept: they point to Jobs and Pods that do not
+// exist once the object is applied elsewhere.
+var jobStatusRuntimeFields = []string{"active", "lastScheduleTime", "lastSuccessfulTime"}
+
+// JobCleaner cleans Job-specific fields.
+type JobCleaner struct {
+	genericCleaner ObjectCleaner
+}
+
+func (c *JobCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
+	c.genericCleaner.Clean(obj, options) // Defaults (backoffLimit, completions, ...) are removed here
+
+	if obj.Spec != nil {
+		if manual, _ := obj.Spec["manualSelector"].(bool); !manual {
+			removeJobLabels(obj.Metadata, obj.Changes, "JobCleaner")
+		}
+		cleanJobSpec(obj.Spec, options, obj.Changes, "JobCleaner")
+		if options.RemoveSuspend {
+			obj.Changes.remove(obj.Spec, "suspend", "JobCleaner:option")
+		}
+	}
+	removeRuntimeStatus(obj, options, jobStatusRuntimeFields, "JobCleaner")
+	if options.RemoveEmpty {
+		cleanupEmptyTopLevelFields(obj)
+	}
+}
+
+// CronJobCleaner cleans CronJob-specific fields, including the Job template.
+type CronJobCleaner struct {
+	genericCleaner ObjectCleaner
+}
+
+func (c *CronJobCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
+	c.genericCleaner.Clean(obj, options)
+
+	if obj.Spec != nil {
+		if jobTemplate, ok := obj.Spec["jobTemplate"].(map[string]interface{}); ok {
+			if templateMeta, ok := jobTemplate["metadata"].(map[string]interface{}); ok {
+				cleanTemplateMetadata(templateMeta, options, obj.Changes)
+				if len(templateMeta) == 0 {
+					obj.Changes.remove(jobTemplate, "metadata", "CronJobCleaner:empty")
+				}
+			}
+			if jobSpec, ok := jobTemplate["spec"].(map[string]interface{}); ok {
+				// The Job template gets the same defaults as a Job
+				if shouldRemoveDefaults(options) {
+					removeDefaults(jobSpec, kindSpecDefaults["Job"], obj.Changes, "CronJobCleaner:default")
+				}
+				cleanJobSpec(jobSpec, options, obj.Changes, "CronJobCleaner")
+			}
+		}
+		if options.RemoveSuspend {
+			obj.Changes.remove(obj.Spec, "suspend", "CronJobCleaner:option")
+		}
+	}
+	removeRuntimeStatus(obj, options, jobStatusRuntimeFields, "CronJobCleaner")
+	if options.RemoveEmpty {
+		cleanupEmptyTopLevelFields(obj)
+	}
+}
+
+// cleanJobSpec removes the selector and template labels the API server generates for a Job (they
+// hold the uid of the Job, so the Job cannot be applied again) and cleans the pod template. Both
+// are kept when the Job sets manualSelector.
+func cleanJobSpec(jobSpec map[string]interface{}, options *CleanupOptions, changes *ChangeRecorder, cleaner string) {
+	template, _ := jobSpec["template"].(map[string]interface{})
+	if manual, _ := jobSpec["manualSelector"].(bool); !manual {
+		changes.remove(jobSpec, "selector", cleaner+":runtime")
+		templateMeta, _ := template["metadata"].(map[string]interface{})
+		removeJobLabels(templateMeta, changes, cleaner)
+	}
+	if template != nil {
+		cleanPodTemplate(template, options, changes)
+	}
+}
+
+// removeJobLabels removes the labels the API server adds to a Job and its pod template from the
+// labels of metadata.
+func removeJobLabels(metadata map[string]interface{}, changes *ChangeRecorder, cleaner string) {
+	labels, ok := metadata["labels"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, key := range controllerPodLabels["Job"] {
+		changes.remove(labels, key, cleaner+":runtime")
+	}
+	if len(labels) == 0 {
+		changes.remove(metadata, "labels", cleaner+":empty")
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestJobCleaners(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  func(options *CleanupOptions)
+		expected string
+	}{
+		{
+			name: "keeps a manual selector and its labels",
+			input: `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: batch
+  labels: {job-name: batch}
+spec:
+  manualSelector: true
+  selector: {matchLabels: {job-name: batch}}
+  template:
+    metadata: {labels: {job-name: batch}}
+    spec:
+      restartPolicy: Never
+      containers: [{name: batch, image: busybox}]
+`,
+			expected: `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: batch
+  labels: {job-name: batch}
+spec:
+  manualSelector: true
+  selector: {matchLabels: {job-name: batch}}
+  template:
+    metadata: {labels: {job-name: batch}}
+    spec:
+      restartPolicy: Never
+      containers: [{name: batch, image: busybox}]
+`,
+		},
+		{
+			name: "removes suspend when enabled",
+			input: `
+apiVersion: batch/v1
+kind: Job
+metadata: {name: batch}
+spec:
+  suspend: true
+  template:
+    spec:
+      restartPolicy: Never
+      containers: [{name: batch, image: busybox}]
+`,
+			options: func(options *CleanupOptions) { options.RemoveSuspend = true },
+			expected: `
+apiVersion: batch/v1
+kind: Job
+metadata: {name: batch}
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers: [{name: batch, image: busybox}]
+`,
+		},
+		{
+			name: "removes suspend of a CronJob when enabled",
+			input: `
+apiVersion: batch/v1
+kind: CronJob
+metadata: {name: report}
+spec:
+  schedule: "0 6 * * *"
+  suspend: true
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          restartPolicy: OnFailure
+          containers: [{name: report, image: busybox}]
+`,
+			options: func(options *CleanupOptions) { options.RemoveSuspend = true },
+			expected: `
+apiVersion: batch/v1
+kind: CronJob
+metadata: {name: report}
+spec:
+  schedule: "0 6 * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          restartPolicy: OnFailure
+          containers: [{name: report, image: busybox}]
+`,
+		},
+		{
+			name: "removes running Jobs from a kept status",
+			input: `
+apiVersion: batch/v1
+kind: CronJob
+metadata: {name: report}
+spec:
+  schedule: "0 6 * * *"
+  jobTemplate:
+    spec:
+      parallelism: 1
+      selector: {matchLabels: {controller-uid: 3d2b6f90}}
+      template:
+        metadata: {labels: {app: report, controller-uid: 3d2b6f90}}
+        spec:
+          restartPolicy: OnFailure
+          containers: [{name: report, image: busybox}]
+status:
+  active: [{kind: Job, name: report-28578600}]
+  lastScheduleTime: "2024-05-02T06:00:00Z"
+  lastSuccessfulTime: "2024-05-01T06:01:12Z"
+`,
+			options: func(options *CleanupOptions) { options.RemoveStatus = false },
+			expected: `
+apiVersion: batch/v1
+kind: CronJob
+metadata: {name: report}
+spec:
+  schedule: "0 6 * * *"
+  jobTemplate:
+    spec:
+      template:
+        metadata: {labels: {app: report}}
+        spec:
+          restartPolicy: OnFailure
+          containers: [{name: report, image: busybox}]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var obj, expected KubernetesObject
+			if err := yaml.Unmarshal([]byte(tt.input), &obj); err != nil {
+				t.Fatalf("failed to parse input: %v", err)
+			}
+			if err := yaml.Unmarshal([]byte(tt.expected), &expected); err != nil {
+				t.Fatalf("failed to parse expected output: %v", err)
+			}
+			normalizeObject(&obj)
+			normalizeObject(&expected)
+
+			options := defaultCleanupOptions()
+			if tt.options != nil {
+				tt.options(options)
+			}
+			cleanObject(&obj, options)
+			if !valuesEqual(objectToMap(&expected), objectToMap(&obj)) {
+				t.Errorf("Unexpected result.\nExpected: %v\nActual: %v", objectToMap(&expected), objectToMap(&obj))
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	yamlv3 "go.yaml.in/yaml/v3"
+	"gopkg.in/yaml.v2"
+)
+
+// Output formats for CleanupOptions.OutputFormat.
+const (
+	outputFormatYAML  = "yaml"  // YAML documents separated by "---"
+	outputFormatJSON  = "json"  // Indented JSON objects, one after the other (like jq)
+	outputFormatJSONL = "jsonl" // One compact JSON object per line
+)
+
+// documentReader reads the objects of a manifest one at a time. next returns io.EOF at the end.
+type documentReader interface {
+	next() (obj KubernetesObject, source *yamlv3.Node, err error)
+	format() string
+}
+
+// newDocumentReader detects whether the input is JSON (an object, an array of objects or a JSON
+// Lines stream) or YAML, and returns the matching reader.
+func newDocumentReader(reader *bufio.Reader) documentReader {
+	if isJSONInput(reader) {
+		return &jsonDocumentReader{decoder: json.NewDecoder(reader)}
+	}
+	return &yamlDocumentReader{decoder: yamlv3.NewDecoder(reader)}
+}
+
+// isJSONInput reports whether the first non-whitespace character of the input starts a JSON
+// object or array. YAML documents essentially never start with one.
+func isJSONInput(reader *bufio.Reader) bool {
+	for n := 1; n <= reader.Size(); n++ {
+		peeked, _ := reader.Peek(n)
+		if len(peeked) < n {
+			return false
+		}
+		switch c := peeked[n-1]; c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		default:
+			return c == '{' || c == '['
+		}
+	}
+	return false
+}
+
+// yamlDocumentReader reads YAML documents, keeping their node trees to preserve formatting.
+type yamlDocumentReader struct {
+	decoder *yamlv3.Decoder
+}
+
+func (r *yamlDocumentReader) format() string { return "YAML" }
+
+func (r *yamlDocumentReader) next() (KubernetesObject, *yamlv3.Node, error) {
+	var obj KubernetesObject
+	var document yamlv3.Node
+	if err := r.decoder.Decode(&document); err != nil {
+		return obj, nil, err
+	}
+	if err := decodeNode(&document, &obj); err != nil {
+		return obj, nil, err
+	}
+	if len(document.Content) == 1 {
+		attachNodes(&obj, document.Content[0])
+	}
+	return obj, &document, nil
+}
+
+// jsonDocumentReader reads a stream of JSON values. Arrays are read as a sequence of objects.
+type jsonDocumentReader struct {
+	decoder *json.Decoder
+	pending []json.RawMessage // Remaining elements of the array being read
+}
+
+func (r *jsonDocumentReader) format() string { return "JSON" }
+
+func (r *jsonDocumentReader) next() (KubernetesObject, *yamlv3.Node, error) {
+	var obj KubernetesObject
+	for len(r.pending) == 0 {
+		var raw json.RawMessage
+		if err := r.decoder.Decode(&raw); err != nil {
+			return obj, nil, err
+		}
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 && raw[0] == '[' {
+			if err := json.Unmarshal(raw, &r.pending); err != nil {
+				return obj, nil, err
+			}
+			continue
+		}
+		r.pending = []json.RawMessage{raw}
+	}
+	raw := r.pending[0]
+	r.pending = r.pending[1:]
+	return obj, nil, decodeJSONObject(raw, &obj)
+}
+
+// decodeJSONObject decodes a JSON object into obj with the same value types as YAML input
+// (integers stay integers rather than becoming float64).
+func decodeJSONObject(raw []byte, obj *KubernetesObject) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	if _, ok := value.(map[string]interface{}); !ok {
+		return fmt.Errorf("expected a JSON object, got %s", bytes.TrimSpace(raw))
+	}
+	encoded, err := yaml.Marshal(jsonNumbers(value))
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(encoded, obj)
+}
+
+// jsonNumbers replaces the json.Number values below data with int64 or float64 values.
+func jsonNumbers(data interface{}) interface{} {
+	switch value := data.(type) {
+	case map[string]interface{}:
+		for k, v := range value {
+			value[k] = jsonNumbers(v)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = jsonNumbers(item)
+		}
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		f, _ := value.Float64()
+		return f
+	}
+	return data
+}
+
+// writeNodeJSON writes node as compact JSON, keeping the order of mapping keys.
+func writeNodeJSON(buf *bytes.Buffer, node *yamlv3.Node) error {
+	switch node.Kind {
+	case yamlv3.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeNodeJSON(buf, node.Content[0])
+	case yamlv3.AliasNode:
+		return writeNodeJSON(buf, node.Alias)
+	case yamlv3.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(node.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeNodeJSON(buf, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yamlv3.SequenceNode:
+		buf.WriteByte('[')
+		for i, child := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeNodeJSON(buf, child); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		encoded, err := json.Marshal(nodeValue(node))
+		if err != nil {
+			return fmt.Errorf("value %q cannot be written as JSON: %w", node.Value, err)
+		}
+		buf.Write(encoded)
+	}
+	return nil
+}
+
+// writeJSON writes node as the next JSON object of the output, indented unless format is JSON Lines.
+func (d *documentWriter) writeJSON(node *yamlv3.Node) error {
+	var compact bytes.Buffer
+	if err := writeNodeJSON(&compact, node); err != nil {
+		return err
+	}
+	output := compact.Bytes()
+	if d.format == outputFormatJSON {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, compact.Bytes(), "", "  "); err != nil {
+			return err
+		}
+		output = indented.Bytes()
+	}
+	if _, err := d.w.Write(append(output, '\n')); err != nil {
+		return err
+	}
+	d.count++
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCleanupManifestJSON(t *testing.T) {
+	deployment := `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web","namespace":"shop","uid":"0b7e2f4c"},` +
+		`"spec":{"replicas":2,"revisionHistoryLimit":10,"template":{"spec":{"containers":[{"name":"app","image":"nginx:1.25","imagePullPolicy":"IfNotPresent"}]}}}}`
+	configMap := `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings","resourceVersion":"42"},"data":{"port":"8080"}}`
+	cleanedDeployment := `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web"},"spec":{"replicas":2,"template":{"spec":{"containers":[{"name":"app","image":"nginx:1.25"}]}}}}`
+	cleanedConfigMap := `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings"},"data":{"port":"8080"}}`
+
+	tests := []struct {
+		name         string
+		input        string
+		outputFormat string
+		expected     string
+	}{
+		{
+			name:         "JSON object to YAML",
+			input:        configMap,
+			outputFormat: outputFormatYAML,
+			expected:     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  port: \"8080\"\n",
+		},
+		{
+			name:         "JSON Lines stream",
+			input:        deployment + "\n" + configMap + "\n",
+			outputFormat: outputFormatJSONL,
+			expected:     cleanedDeployment + "\n" + cleanedConfigMap + "\n",
+		},
+		{
+			name:         "JSON array of objects",
+			input:        "[" + deployment + ",\n" + configMap + "]",
+			outputFormat: outputFormatJSONL,
+			expected:     cleanedDeployment + "\n" + cleanedConfigMap + "\n",
+		},
+		{
+			name:         "JSON List",
+			input:        `{"apiVersion":"v1","kind":"List","metadata":{"resourceVersion":""},"items":[` + configMap + `]}`,
+			outputFormat: outputFormatJSONL,
+			expected:     `{"apiVersion":"v1","kind":"List","items":[` + cleanedConfigMap + "]}\n",
+		},
+		{
+			name:         "YAML to indented JSON",
+			input:        "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n  namespace: shop\ndata:\n  port: \"8080\"\n",
+			outputFormat: outputFormatJSON,
+			expected:     "{\n  \"apiVersion\": \"v1\",\n  \"kind\": \"ConfigMap\",\n  \"metadata\": {\n    \"name\": \"settings\"\n  },\n  \"data\": {\n    \"port\": \"8080\"\n  }\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := defaultCleanupOptions()
+			options.OutputFormat = tt.outputFormat
+			var output bytes.Buffer
+			if err := cleanupManifest(strings.NewReader(tt.input), &output, options); err != nil {
+				t.Fatalf("cleanupManifest returned error: %v", err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("Unexpected output.\nExpected:\n%s\nActual:\n%s", tt.expected, output.String())
+			}
+		})
+	}
+}
+
+func TestIsJSONInput(t *testing.T) {
+	tests := map[string]bool{
+		`{"kind":"Pod"}`:             true,
+		"\n\n  [{\"kind\":\"Pod\"}]": true,
+		"kind: Pod\n":                false,
+		"# {comment}\nkind: Pod":     false,
+		"":                           false,
+	}
+	for input, expected := range tests {
+		if actual := isJSONInput(bufio.NewReader(strings.NewReader(input))); actual != expected {
+			t.Errorf("Input %q: expected %v, got %v", input, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	yamlv3 "go.yaml.in/yaml/v3"
+)
+
+// kustomizationFile is the name of the kustomization written to the split directory by --kustomize.
+const kustomizationFile = "kustomization.yaml"
+
+// Kustomization fields that shared labels are moved to (--kustomize-labels).
+const (
+	kustomizeLabels       = "labels"       // Only added to metadata.labels (includeSelectors: false)
+	kustomizeCommonLabels = "commonLabels" // Also added to selectors and pod templates by kustomize
+)
+
+// kustomization is the kustomization.yaml written next to the split files.
+type kustomization struct {
+	APIVersion   string            `yaml:"apiVersion"`
+	Kind         string            `yaml:"kind"`
+	Namespace    string            `yaml:"namespace,omitempty"`
+	CommonLabels map[string]string `yaml:"commonLabels,omitempty"`
+	Labels       []kustomizeLabel  `yaml:"labels,omitempty"`
+	Resources    []string          `yaml:"resources"`
+}
+
+type kustomizeLabel struct {
+	Pairs            map[string]string `yaml:"pairs"`
+	IncludeSelectors bool              `yaml:"includeSelectors"`
+}
+
+// writeKustomization writes a kustomization.yaml listing the files written by split. The namespace
+// removed from the objects becomes the kustomization namespace when they all shared one. With
+// labelsField set, the labels every object carries are removed from the files and moved to that
+// field instead; format is the output format the files were written in.
+func writeKustomization(split *SplitWriter, labelsField, format string) error {
+	if split.used[kustomizationFile] {
+		return fmt.Errorf("an object was written to %s; choose a --split-template that does not produce that name", kustomizationFile)
+	}
+	result := kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Namespace:  commonNamespace(split.Files),
+		Resources:  []string{},
+	}
+	for _, file := range split.Files {
+		result.Resources = append(result.Resources, file.Path)
+	}
+
+	if labelsField != "" {
+		shared := sharedLabels(split.Files)
+		if len(shared) > 0 {
+			keys := slices.Sorted(maps.Keys(shared))
+			for _, file := range split.Files {
+				if err := removeFileLabels(filepath.Join(split.Dir, filepath.FromSlash(file.Path)), keys, format); err != nil {
+					return err
+				}
+			}
+			if labelsField == kustomizeCommonLabels {
+				result.CommonLabels = shared
+			} else {
+				result.Labels = []kustomizeLabel{{Pairs: shared}}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yamlv3.NewEncoder(&buf)
+	encoder.SetIndent(defaultIndent)
+	encoder.CompactSeqIndent()
+	if err := encoder.Encode(result); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	return writeMirroredFile(filepath.Join(split.Dir, kustomizationFile), buf.Bytes())
+}
+
+// commonNamespace returns the namespace cleaning removed from the objects of files, or "" when it
+// removed none or the objects came from several namespaces.
+func commonNamespace(files []SplitFile) string {
+	var namespaces []string
+	for _, file := range files {
+		if file.NamespaceRemoved && !slices.Contains(namespaces, file.Namespace) {
+			namespaces = append(namespaces, file.Namespace)
+		}
+	}
+	if len(namespaces) > 1 {
+		slices.Sort(namespaces)
+		log.Printf("Warning: objects come from several namespaces (%s); the kustomization sets no namespace", strings.Join(namespaces, ", "))
+		return ""
+	}
+	if len(namespaces) == 0 {
+		return ""
+	}
+	return namespaces[0]
+}
+
+// sharedLabels returns the labels that every file carries with the same value. A single object has
+// nothing to share, so nil is returned for fewer than two files.
+func sharedLabels(files []SplitFile) map[string]string {
+	if len(files) < 2 {
+		return nil
+	}
+	shared := map[string]string{}
+	for key, value := range files[0].Labels {
+		shared[key] = value
+	}
+	for _, file := range files[1:] {
+		for key, value := range shared {
+			if other, ok := file.Labels[key]; !ok || other != value {
+				delete(shared, key)
+			}
+		}
+	}
+	return shared
+}
+
+// removeFileLabels removes the given metadata.labels keys from the object written to path, keeping
+// the rest of the file as it is.
+func removeFileLabels(path string, keys []string, format string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading '%s': %w", path, err)
+	}
+	var document yamlv3.Node
+	if err := yamlv3.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("error decoding '%s': %w", path, err)
+	}
+	if len(document.Content) != 1 {
+		return nil
+	}
+	metadata := mappingValue(document.Content[0], "metadata")
+	labels := mappingValue(metadata, "labels")
+	if labels == nil {
+		return nil
+	}
+	for _, key := range keys {
+		deleteMappingKey(labels, key)
+	}
+	if len(labels.Content) == 0 {
+		deleteMappingKey(metadata, "labels")
+	}
+
+	var buf bytes.Buffer
+	writer := &documentWriter{w: &buf, format: format}
+	if format == outputFormatJSON || format == outputFormatJSONL {
+		err = writer.writeJSON(&document)
+	} else {
+		indent, compactSeq := nodeIndentation(&document)
+		err = writer.write(&document, indent, compactSeq)
+	}
+	if err != nil {
+		return fmt.Errorf("error encoding '%s': %w", path, err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("error writing output file '%s': %w", path, err)
+	}
+	return nil
+}
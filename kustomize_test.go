@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteKustomization(t *testing.T) {
+	input := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: shop
+  labels:
+    app.kubernetes.io/part-of: shop
+    tier: config
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: shop
+  labels:
+    app.kubernetes.io/part-of: shop
+    tier: web
+spec:
+  selector:
+    app: web
+`
+	tests := []struct {
+		name          string
+		labelsField   string
+		expected      string
+		expectedFiles map[string]string
+	}{
+		{
+			name:        "resources and namespace",
+			labelsField: "",
+			expected: `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+namespace: shop
+resources:
+- shop/configmap-settings.yaml
+- shop/service-web.yaml
+`,
+			expectedFiles: map[string]string{
+				"shop/configmap-settings.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n  labels:\n    app.kubernetes.io/part-of: shop\n    tier: config\n",
+			},
+		},
+		{
+			name:        "shared labels",
+			labelsField: kustomizeLabels,
+			expected: `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+namespace: shop
+labels:
+- pairs:
+    app.kubernetes.io/part-of: shop
+  includeSelectors: false
+resources:
+- shop/configmap-settings.yaml
+- shop/service-web.yaml
+`,
+			expectedFiles: map[string]string{
+				"shop/configmap-settings.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n  labels:\n    tier: config\n",
+				"shop/service-web.yaml":        "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  labels:\n    tier: web\nspec:\n  selector:\n    app: web\n",
+			},
+		},
+		{
+			name:        "shared labels as common labels",
+			labelsField: kustomizeCommonLabels,
+			expected: `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+namespace: shop
+commonLabels:
+  app.kubernetes.io/part-of: shop
+resources:
+- shop/configmap-settings.yaml
+- shop/service-web.yaml
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			split, err := NewSplitWriter(dir, defaultSplitTemplate)
+			if err != nil {
+				t.Fatalf("NewSplitWriter returned error: %v", err)
+			}
+			options := defaultCleanupOptions()
+			options.Split = split
+			if err := cleanupManifest(strings.NewReader(input), &bytes.Buffer{}, options); err != nil {
+				t.Fatalf("cleanupManifest returned error: %v", err)
+			}
+			if err := writeKustomization(split, tt.labelsField, options.OutputFormat); err != nil {
+				t.Fatalf("writeKustomization returned error: %v", err)
+			}
+
+			actual, err := os.ReadFile(filepath.Join(dir, kustomizationFile))
+			if err != nil {
+				t.Fatalf("Expected %s: %v", kustomizationFile, err)
+			}
+			if string(actual) != tt.expected {
+				t.Errorf("Unexpected kustomization.\nExpected:\n%s\nActual:\n%s", tt.expected, actual)
+			}
+			for path, content := range tt.expectedFiles {
+				data, err := os.ReadFile(filepath.Join(dir, path))
+				if err != nil {
+					t.Errorf("Expected file %s: %v", path, err)
+					continue
+				}
+				if string(data) != content {
+					t.Errorf("Unexpected content of %s.\nExpected:\n%s\nActual:\n%s", path, content, data)
+				}
+			}
+		})
+	}
+}
+
+func TestCommonNamespace(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []SplitFile
+		expected string
+	}{
+		{
+			name:     "cluster-scoped objects are ignored",
+			files:    []SplitFile{{Namespace: "shop", NamespaceRemoved: true}, {Namespace: ""}},
+			expected: "shop",
+		},
+		{
+			name:     "several namespaces",
+			files:    []SplitFile{{Namespace: "shop", NamespaceRemoved: true}, {Namespace: "billing", NamespaceRemoved: true}},
+			expected: "",
+		},
+		{
+			name:     "namespaces kept",
+			files:    []SplitFile{{Namespace: "shop"}},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := commonNamespace(tt.files); actual != tt.expected {
+				t.Errorf("Unexpected namespace.\nExpected: %q\nActual: %q", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// namespaceNamePattern matches valid namespace names (DNS labels).
+var namespaceNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// namespaceNameLabel is the label holding the name of every Namespace, used by namespace selectors.
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// namespaceReferenceParents are the fields whose namespace key references a namespace: object
+// metadata and object references (RoleBinding subjects, PersistentVolume claimRef, webhook
+// clientConfig.service, APIService spec.service, CSI secret references, Gateway API references,
+// ...). Elements of a list count as stored under the key of the list. Other namespace keys (e.g.
+// in ConfigMap data or custom resource specs) are left alone.
+var namespaceReferenceParents = []string{
+	"metadata", "subjects", "claimRef", "service", "targetRef", "involvedObject", "secretRef",
+	"nodePublishSecretRef", "nodeStageSecretRef", "nodeExpandSecretRef", "controllerPublishSecretRef", "controllerExpandSecretRef",
+	"parentRefs", "backendRefs", "certificateRefs", "from",
+}
+
+// validateNamespaceName checks that name can be used as a namespace.
+func validateNamespaceName(name string) error {
+	if len(name) > 63 || !namespaceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid namespace %q: must be a lowercase DNS label of at most 63 characters", name)
+	}
+	return nil
+}
+
+// namespaceRetargeter rewrites the references to the namespaces of a mapping.
+type namespaceRetargeter struct {
+	targets         map[string]string
+	hostnames       *regexp.Regexp // <service>.<namespace>.svc[.cluster.local]
+	serviceAccounts *regexp.Regexp // system:serviceaccount:<namespace>:<name> and system:serviceaccounts:<namespace>
+}
+
+// newNamespaceRetargeter returns a retargeter for targets, a map from source to target namespace.
+func newNamespaceRetargeter(targets map[string]string) *namespaceRetargeter {
+	sources := slices.Sorted(maps.Keys(targets))
+	for i, source := range sources {
+		sources[i] = regexp.QuoteMeta(source)
+	}
+	alternatives := strings.Join(sources, "|")
+	return &namespaceRetargeter{
+		targets:         targets,
+		hostnames:       regexp.MustCompile(`([a-z0-9])\.(` + alternatives + `)\.svc\b`),
+		serviceAccounts: regexp.MustCompile(`^(system:serviceaccounts?:)(` + alternatives + `)(:|$)`),
+	}
+}
+
+// retargetNamespaces rewrites every reference to a namespace of options.NamespaceMap in obj: its
+// own namespace (or name, for a Namespace), namespace fields of references (see
+// namespaceReferenceParents), namespace lists and selectors, service account user and group
+// names, and Service hostnames in any string value (env vars, ConfigMap data, args, ...).
+func retargetNamespaces(obj *KubernetesObject, options *CleanupOptions) {
+	if len(options.NamespaceMap) == 0 {
+		return
+	}
+	retargeter := newNamespaceRetargeter(options.NamespaceMap)
+	if target, ok := options.NamespaceMap[obj.namespace]; ok {
+		obj.namespace = target
+	}
+	if obj.Kind == "Namespace" && obj.Metadata != nil {
+		if name, ok := obj.Metadata["name"].(string); ok {
+			if target, ok := options.NamespaceMap[name]; ok {
+				obj.Metadata["name"] = target
+			}
+		}
+	}
+	fields := map[string]map[string]interface{}{"metadata": obj.Metadata, "spec": obj.Spec, "data": obj.Data, "stringData": obj.StringData}
+	for key, m := range fields {
+		if m != nil {
+			retargeter.rewrite(m, key)
+		}
+	}
+	if obj.Extra != nil {
+		retargeter.rewrite(obj.Extra, "") // Top-level fields, e.g. subjects
+	}
+}
+
+// rewrite returns value, stored under key, with the namespace references below it rewritten.
+// Maps and lists are updated in place; list elements are rewritten with the key of their list.
+func (r *namespaceRetargeter) rewrite(value interface{}, key string) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		selectsNamespaces := key == "matchExpressions" && typed["key"] == namespaceNameLabel
+		for childKey, child := range typed {
+			if name, ok := child.(string); ok && isNamespaceReference(key, childKey) {
+				if target, ok := r.targets[name]; ok {
+					typed[childKey] = target
+					continue
+				}
+			}
+			if names, ok := child.([]interface{}); ok && (childKey == "namespaces" || (childKey == "values" && selectsNamespaces)) {
+				// Pod affinity terms and similar lists of namespace names, and selector expressions
+				// on the namespace name label
+				for i, item := range names {
+					if target, ok := r.targets[fmt.Sprint(item)]; ok {
+						names[i] = target
+					}
+				}
+				continue
+			}
+			typed[childKey] = r.rewrite(child, childKey)
+		}
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = r.rewrite(item, key)
+		}
+	case string:
+		return r.rewriteString(typed)
+	}
+	return value
+}
+
+// isNamespaceReference reports whether the string stored at key, in a map stored under parent,
+// names a namespace.
+func isNamespaceReference(parent, key string) bool {
+	switch key {
+	case "namespace":
+		return slices.Contains(namespaceReferenceParents, parent)
+	case namespaceNameLabel:
+		return parent == "labels" || parent == "matchLabels"
+	}
+	return false
+}
+
+// rewriteString rewrites service account names and Service hostnames in s.
+func (r *namespaceRetargeter) rewriteString(s string) string {
+	s = r.serviceAccounts.ReplaceAllStringFunc(s, func(match string) string {
+		parts := r.serviceAccounts.FindStringSubmatch(match)
+		return parts[1] + r.targets[parts[2]] + parts[3]
+	})
+	return r.hostnames.ReplaceAllStringFunc(s, func(match string) string {
+		parts := r.hostnames.FindStringSubmatch(match)
+		return parts[1] + "." + r.targets[parts[2]] + ".svc"
+	})
+}
+
+// manifestNamespaces returns the namespaces of the objects in a manifest, including the names of
+// Namespace objects, in the order they first appear.
+func manifestNamespaces(input io.Reader) ([]string, error) {
+	documents := newDocumentReader(bufio.NewReader(input))
+	var namespaces []string
+	var collect func(obj *KubernetesObject)
+	collect = func(obj *KubernetesObject) {
+		names := []interface{}{obj.Metadata["namespace"]}
+		if obj.Kind == "Namespace" {
+			names = append(names, obj.Metadata["name"])
+		}
+		for _, name := range names {
+			if name, ok := name.(string); ok && name != "" && !slices.Contains(namespaces, name) {
+				namespaces = append(namespaces, name)
+			}
+		}
+		for i := range obj.Items {
+			collect(&obj.Items[i])
+		}
+	}
+	for {
+		obj, _, err := documents.next()
+		if errors.Is(err, io.EOF) {
+			return namespaces, nil
+		}
+		if err != nil {
+			return namespaces, err
+		}
+		collect(&obj)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRetargetNamespaces(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "object namespace and service hostnames",
+			input: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: shop
+data:
+  db: postgres://db.shop.svc.cluster.local:5432/app
+  cache: cache.shopping.svc:6379
+`,
+			expected: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: staging
+data:
+  db: postgres://db.staging.svc.cluster.local:5432/app
+  cache: cache.shopping.svc:6379
+`,
+		},
+		{
+			name: "binding subjects and service account names",
+			input: `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: web
+subjects:
+- kind: ServiceAccount
+  name: web
+  namespace: shop
+- kind: User
+  name: system:serviceaccount:shop:web
+- kind: Group
+  name: system:serviceaccounts:shop
+- kind: ServiceAccount
+  name: controller
+  namespace: kube-system
+roleRef:
+  kind: ClusterRole
+  name: view
+`,
+			expected: `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: web
+subjects:
+- kind: ServiceAccount
+  name: web
+  namespace: staging
+- kind: User
+  name: system:serviceaccount:staging:web
+- kind: Group
+  name: system:serviceaccounts:staging
+- kind: ServiceAccount
+  name: controller
+  namespace: kube-system
+roleRef:
+  kind: ClusterRole
+  name: view
+`,
+		},
+		{
+			name: "namespace objects and selectors",
+			input: `apiVersion: v1
+kind: Namespace
+metadata:
+  name: shop
+  labels:
+    kubernetes.io/metadata.name: shop
+`,
+			expected: `apiVersion: v1
+kind: Namespace
+metadata:
+  name: staging
+  labels:
+    kubernetes.io/metadata.name: staging
+`,
+		},
+		{
+			name: "webhook services",
+			input: `apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: hook
+webhooks:
+- name: hook.example.com
+  clientConfig:
+    service:
+      name: hook
+      namespace: shop
+`,
+			expected: `apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: hook
+webhooks:
+- name: hook.example.com
+  clientConfig:
+    service:
+      name: hook
+      namespace: staging
+`,
+		},
+		{
+			name: "only references",
+			input: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: shop
+  annotations:
+    namespace: shop
+data:
+  namespace: shop
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: web
+  namespace: shop
+spec:
+  ingress:
+  - from:
+    - namespaceSelector:
+        matchExpressions:
+        - key: kubernetes.io/metadata.name
+          operator: In
+          values: [shop, billing]
+---
+apiVersion: example.com/v1
+kind: Tenant
+metadata:
+  name: web
+spec:
+  namespace: shop
+  labels:
+    namespace: shop
+`,
+			expected: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: staging
+  annotations:
+    namespace: shop
+data:
+  namespace: shop
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: web
+  namespace: staging
+spec:
+  ingress:
+  - from:
+    - namespaceSelector:
+        matchExpressions:
+        - key: kubernetes.io/metadata.name
+          operator: In
+          values: [staging, billing]
+---
+apiVersion: example.com/v1
+kind: Tenant
+metadata:
+  name: web
+spec:
+  namespace: shop
+  labels:
+    namespace: shop
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := defaultCleanupOptions()
+			options.KeyOrder = keyOrderPreserve
+			options.NamespaceMap = map[string]string{"shop": "staging"}
+			var output bytes.Buffer
+			if err := cleanupManifest(strings.NewReader(tt.input), &output, options); err != nil {
+				t.Fatalf("cleanupManifest returned error: %v", err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("Unexpected output.\nExpected:\n%s\nActual:\n%s", tt.expected, output.String())
+			}
+		})
+	}
+}
+
+func TestManifestNamespaces(t *testing.T) {
+	input := `apiVersion: v1
+kind: Namespace
+metadata:
+  name: shop
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: settings
+    namespace: billing
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: settings
+    namespace: shop
+`
+	namespaces, err := manifestNamespaces(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("manifestNamespaces returned error: %v", err)
+	}
+	if expected := []string{"shop", "billing"}; !reflect.DeepEqual(namespaces, expected) {
+		t.Errorf("Unexpected namespaces.\nExpected: %v\nActual: %v", expected, namespaces)
+	}
+}
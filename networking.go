@@ -0,0 +1,203 @@
+package main
+
+import "strings"
+
+// gatewayAPIGroup is the API group of the Gateway API. Other APIs use the same kind names (e.g.
+// the Istio Gateway), so Gateway API defaults only apply to objects of this group.
+const gatewayAPIGroup = "gateway.networking.k8s.io"
+
+// ingressPathDefaults apply to every path of an Ingress rule. Only the beta APIs default pathType;
+// networking.k8s.io/v1 requires it.
+var ingressPathDefaults = []fieldDefault{
+	{path: "pathType", value: "ImplementationSpecific"},
+}
+
+// networkPolicyPortDefaults apply to every port of NetworkPolicy ingress and egress rules.
+var networkPolicyPortDefaults = []fieldDefault{
+	{path: "protocol", value: "TCP"},
+}
+
+// networkPolicyDefaults apply to the spec of a NetworkPolicy: without policyTypes, the API server
+// sets Ingress, and Egress when the policy has egress rules. An empty egress list has none, so
+// [Ingress, Egress] with egress: [] denies all egress and is not a default.
+var networkPolicyDefaults = []fieldDefault{
+	{path: "policyTypes", valueFrom: func(spec map[string]interface{}) interface{} {
+		if egress, _ := spec["egress"].([]interface{}); len(egress) > 0 {
+			return []interface{}{"Ingress", "Egress"}
+		}
+		return []interface{}{"Ingress"}
+	}},
+}
+
+// ingressClassDefaults apply to the spec of an IngressClass.
+var ingressClassDefaults = []fieldDefault{
+	{path: "parameters.scope", value: "Cluster"},
+}
+
+// Gateway API defaults, from the kubebuilder defaults of the CRDs.
+var (
+	gatewayListenerDefaults = []fieldDefault{
+		{path: "allowedRoutes.namespaces.from", value: "Same"},
+		{path: "tls.mode", value: "Terminate"},
+	}
+	certificateRefDefaults = []fieldDefault{
+		{path: "group", value: ""},
+		{path: "kind", value: "Secret"},
+	}
+	parentRefDefaults = []fieldDefault{
+		{path: "group", value: gatewayAPIGroup},
+		{path: "kind", value: "Gateway"},
+	}
+	backendRefDefaults = []fieldDefault{
+		{path: "group", value: ""},
+		{path: "kind", value: "Service"},
+		{path: "weight", value: 1},
+	}
+	httpRouteMatchDefaults = []fieldDefault{
+		{path: "path.type", value: "PathPrefix"},
+	}
+	httpHeaderMatchDefaults = []fieldDefault{
+		{path: "type", value: "Exact"},
+	}
+	// A rule without matches matches every request
+	defaultHTTPRouteMatches = []interface{}{
+		map[string]interface{}{"path": map[string]interface{}{"type": "PathPrefix", "value": "/"}},
+	}
+)
+
+// IngressCleaner cleans Ingress-specific fields.
+type IngressCleaner struct {
+	genericCleaner ObjectCleaner
+}
+
+func (c *IngressCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
+	c.genericCleaner.Clean(obj, options)
+
+	if obj.Spec != nil && shouldRemoveDefaults(options) && strings.HasSuffix(obj.APIVersion, "/v1beta1") {
+		rules, _ := obj.Spec["rules"].([]interface{})
+		for _, rule := range rules {
+			ruleMap, _ := rule.(map[string]interface{})
+			if http, ok := ruleMap["http"].(map[string]interface{}); ok {
+				removeListDefaults(http, "paths", ingressPathDefaults, obj.Changes, "IngressCleaner:default")
+			}
+		}
+	}
+	removeRuntimeStatus(obj, options, []string{"loadBalancer"}, "IngressCleaner")
+	if options.RemoveEmpty {
+		cleanupEmptyTopLevelFields(obj)
+	}
+}
+
+// IngressClassCleaner cleans IngressClass-specific fields.
+type IngressClassCleaner struct {
+	genericCleaner ObjectCleaner
+}
+
+func (c *IngressClassCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
+	c.genericCleaner.Clean(obj, options) // Keeps ingressclass.kubernetes.io/is-default-class
+
+	if obj.Spec != nil && shouldRemoveDefaults(options) {
+		removeDefaults(obj.Spec, ingressClassDefaults, obj.Changes, "IngressClassCleaner:default")
+	}
+	if options.RemoveEmpty {
+		cleanupEmptyTopLevelFields(obj)
+	}
+}
+
+// NetworkPolicyCleaner cleans NetworkPolicy-specific fields.
+type NetworkPolicyCleaner struct {
+	genericCleaner ObjectCleaner
+}
+
+func (c *NetworkPolicyCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
+	c.genericCleaner.Clean(obj, options)
+
+	if obj.Spec != nil && shouldRemoveDefaults(options) {
+		removeDefaults(obj.Spec, networkPolicyDefaults, obj.Changes, "NetworkPolicyCleaner:default")
+		for _, direction := range []string{"ingress", "egress"} {
+			rules, _ := obj.Spec[direction].([]interface{})
+			for _, rule := range rules {
+				if ruleMap, ok := rule.(map[string]interface{}); ok {
+					removeListDefaults(ruleMap, "ports", networkPolicyPortDefaults, obj.Changes, "NetworkPolicyCleaner:default")
+				}
+			}
+		}
+	}
+	if options.RemoveEmpty {
+		cleanupEmptyTopLevelFields(obj)
+	}
+}
+
+// GatewayCleaner cleans Gateway API Gateway-specific fields.
+type GatewayCleaner struct {
+	genericCleaner ObjectCleaner
+}
+
+func (c *GatewayCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
+	c.genericCleaner.Clean(obj, options)
+	if !isGatewayAPI(obj) {
+		return // e.g. an Istio Gateway
+	}
+
+	if obj.Spec != nil && shouldRemoveDefaults(options) {
+		listeners, _ := obj.Spec["listeners"].([]interface{})
+		for _, listener := range listeners {
+			listenerMap, _ := listener.(map[string]interface{})
+			if tls, ok := listenerMap["tls"].(map[string]interface{}); ok {
+				removeListDefaults(tls, "certificateRefs", certificateRefDefaults, obj.Changes, "GatewayCleaner:default")
+			}
+		}
+		removeListDefaults(obj.Spec, "listeners", gatewayListenerDefaults, obj.Changes, "GatewayCleaner:default")
+	}
+	// The addresses are assigned by the implementation, conditions describe the source cluster
+	removeRuntimeStatus(obj, options, []string{"addresses", "conditions", "listeners"}, "GatewayCleaner")
+	if options.RemoveEmpty {
+		cleanupEmptyTopLevelFields(obj)
+	}
+}
+
+// HTTPRouteCleaner cleans Gateway API HTTPRoute-specific fields.
+type HTTPRouteCleaner struct {
+	genericCleaner ObjectCleaner
+}
+
+func (c *HTTPRouteCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
+	c.genericCleaner.Clean(obj, options)
+	if !isGatewayAPI(obj) {
+		return
+	}
+
+	if obj.Spec != nil && shouldRemoveDefaults(options) {
+		const reason = "HTTPRouteCleaner:default"
+		removeListDefaults(obj.Spec, "parentRefs", parentRefDefaults, obj.Changes, reason)
+		rules, _ := obj.Spec["rules"].([]interface{})
+		for _, rule := range rules {
+			ruleMap, ok := rule.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if valuesEqual(defaultHTTPRouteMatches, ruleMap["matches"]) {
+				obj.Changes.remove(ruleMap, "matches", reason)
+			}
+			matches, _ := ruleMap["matches"].([]interface{})
+			for _, match := range matches {
+				if matchMap, ok := match.(map[string]interface{}); ok {
+					removeDefaults(matchMap, httpRouteMatchDefaults, obj.Changes, reason)
+					removeListDefaults(matchMap, "headers", httpHeaderMatchDefaults, obj.Changes, reason)
+					removeListDefaults(matchMap, "queryParams", httpHeaderMatchDefaults, obj.Changes, reason)
+				}
+			}
+			removeListDefaults(ruleMap, "backendRefs", backendRefDefaults, obj.Changes, reason)
+		}
+	}
+	// Every parent reports its own conditions
+	removeRuntimeStatus(obj, options, []string{"parents"}, "HTTPRouteCleaner")
+	if options.RemoveEmpty {
+		cleanupEmptyTopLevelFields(obj)
+	}
+}
+
+// isGatewayAPI reports whether obj belongs to the Gateway API group.
+func isGatewayAPI(obj *KubernetesObject) bool {
+	return strings.HasPrefix(obj.APIVersion, gatewayAPIGroup+"/")
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestNetworkingCleaners(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "removes the defaulted pathType of beta Ingresses",
+			input: `
+apiVersion: networking.k8s.io/v1beta1
+kind: Ingress
+metadata: {name: web}
+spec:
+  rules:
+  - http:
+      paths:
+      - {path: /, pathType: ImplementationSpecific, backend: {serviceName: web, servicePort: 80}}
+`,
+			expected: `
+apiVersion: networking.k8s.io/v1beta1
+kind: Ingress
+metadata: {name: web}
+spec:
+  rules:
+  - http:
+      paths:
+      - {path: /, backend: {serviceName: web, servicePort: 80}}
+`,
+		},
+		{
+			name: "removes policy types implied by the rules",
+			input: `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata: {name: web}
+spec:
+  podSelector: {}
+  egress: [{ports: [{port: 53, protocol: UDP}]}]
+  policyTypes: [Ingress, Egress]
+`,
+			expected: `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata: {name: web}
+spec:
+  egress: [{ports: [{port: 53, protocol: UDP}]}]
+`,
+		},
+		{
+			name: "keeps a deny-all egress policy",
+			input: `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata: {name: deny-egress}
+spec:
+  podSelector: {matchLabels: {app: web}}
+  policyTypes: [Egress]
+`,
+			expected: `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata: {name: deny-egress}
+spec:
+  podSelector: {matchLabels: {app: web}}
+  policyTypes: [Egress]
+`,
+		},
+		{
+			name: "keeps the policy types of a deny-all egress policy with empty egress rules",
+			input: `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata: {name: deny-egress}
+spec:
+  podSelector: {}
+  policyTypes: [Ingress, Egress]
+  egress: []
+`,
+			expected: `
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata: {name: deny-egress}
+spec:
+  policyTypes: [Ingress, Egress]
+`,
+		},
+		{
+			name: "leaves Gateways of other APIs alone",
+			input: `
+apiVersion: networking.istio.io/v1
+kind: Gateway
+metadata: {name: shop}
+spec:
+  selector: {istio: ingressgateway}
+  servers: [{port: {number: 80, name: http, protocol: HTTP}, hosts: ["*"]}]
+`,
+			expected: `
+apiVersion: networking.istio.io/v1
+kind: Gateway
+metadata: {name: shop}
+spec:
+  selector: {istio: ingressgateway}
+  servers: [{port: {number: 80, name: http, protocol: HTTP}, hosts: ["*"]}]
+`,
+		},
+		{
+			name: "keeps a match-all route rule with other matches",
+			input: `
+apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata: {name: web}
+spec:
+  rules:
+  - matches: [{path: {type: PathPrefix, value: /}}, {path: {type: Exact, value: /health}}]
+    backendRefs: [{name: web, port: 80, weight: 2}]
+`,
+			expected: `
+apiVersion: gateway.networking.k8s.io/v1
+kind: HTTPRoute
+metadata: {name: web}
+spec:
+  rules:
+  - matches: [{path: {value: /}}, {path: {type: Exact, value: /health}}]
+    backendRefs: [{name: web, port: 80, weight: 2}]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var obj, expected KubernetesObject
+			if err := yaml.Unmarshal([]byte(tt.input), &obj); err != nil {
+				t.Fatalf("failed to parse input: %v", err)
+			}
+			if err := yaml.Unmarshal([]byte(tt.expected), &expected); err != nil {
+				t.Fatalf("failed to parse expected output: %v", err)
+			}
+			normalizeObject(&obj)
+			normalizeObject(&expected)
+
+			cleanObject(&obj, defaultCleanupOptions())
+			if !valuesEqual(objectToMap(&expected), objectToMap(&obj)) {
+				t.Errorf("Unexpected result.\nExpected: %v\nActual: %v", objectToMap(&expected), objectToMap(&obj))
+			}
+		})
+	}
+}
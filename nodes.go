@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	yamlv3 "go.yaml.in/yaml/v3"
+	"gopkg.in/yaml.v2"
+)
+
+// The input is kept as a yaml.v3 node tree next to the decoded object, and the cleaned object is
+// merged back into that tree before encoding. Untouched fields keep their comments, key order and
+// scalar styles, so cleaning a hand-written manifest only changes the lines that were cleaned.
+
+// Output indentation used for documents without a source node, matching kubectl.
+const (
+	defaultIndent     = 2
+	defaultCompactSeq = true // "- item" at the indentation of the parent key
+)
+
+// decodeNode decodes a node into out with the yaml.v2 semantics the cleaners expect
+// (e.g. unquoted timestamps stay strings).
+func decodeNode(node *yamlv3.Node, out interface{}) error {
+	encoded, err := yamlv3.Marshal(node)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(encoded, out)
+}
+
+// nodeValue returns the value of node as the cleaners see it.
+func nodeValue(node *yamlv3.Node) interface{} {
+	if node.Kind == yamlv3.AliasNode {
+		return nodeValue(node.Alias)
+	}
+	var value interface{}
+	if err := decodeNode(node, &value); err != nil {
+		return nil
+	}
+	return normalizeValue(value)
+}
+
+// mappingValue returns the value stored under key in a mapping node, or nil.
+func mappingValue(node *yamlv3.Node, key string) *yamlv3.Node {
+	if node == nil || node.Kind != yamlv3.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// deleteMappingKey removes key and its value from a mapping node.
+func deleteMappingKey(node *yamlv3.Node, key string) {
+	if node == nil || node.Kind != yamlv3.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// attachNodes records the source node of obj and of its List items.
+func attachNodes(obj *KubernetesObject, node *yamlv3.Node) {
+	obj.node = node
+	items := mappingValue(node, "items")
+	if items == nil || items.Kind != yamlv3.SequenceNode || len(items.Content) != len(obj.Items) {
+		return
+	}
+	for i := range obj.Items {
+		attachNodes(&obj.Items[i], items.Content[i])
+	}
+}
+
+// objectNode returns the node to encode for obj: its source node updated with the cleaned values,
+// or a new node when obj was not decoded from YAML.
+func objectNode(obj *KubernetesObject) (*yamlv3.Node, error) {
+	value := map[string]interface{}{}
+	for key, field := range objectToMap(obj) {
+		value[key] = field
+	}
+	if obj.Items != nil {
+		items := &yamlv3.Node{Kind: yamlv3.SequenceNode, Tag: "!!seq"}
+		if original := mappingValue(obj.node, "items"); original != nil && original.Kind == yamlv3.SequenceNode {
+			items.Style = original.Style
+			copyComments(items, original)
+		}
+		for i := range obj.Items {
+			item, err := objectNode(&obj.Items[i])
+			if err != nil {
+				return nil, err
+			}
+			items.Content = append(items.Content, item)
+		}
+		value["items"] = items
+	}
+	if obj.node == nil {
+		return newNode(value)
+	}
+	return mergeNode(obj.node, value)
+}
+
+// newNode encodes value as a node.
+func newNode(value interface{}) (*yamlv3.Node, error) {
+	if node, ok := value.(*yamlv3.Node); ok {
+		return node, nil
+	}
+	node := &yamlv3.Node{}
+	if m, ok := value.(map[string]interface{}); ok {
+		// Encode maps key by key so nested nodes (e.g. List items) are used as they are
+		node.Kind, node.Tag = yamlv3.MappingNode, "!!map"
+		for _, key := range sortedKeys(m) {
+			child, err := newNode(m[key])
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: key}, child)
+		}
+		return node, nil
+	}
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// mergeNode returns node updated to hold value. Unchanged parts of node are reused as they are;
+// removed keys and list elements are dropped, and new ones are appended.
+func mergeNode(node *yamlv3.Node, value interface{}) (*yamlv3.Node, error) {
+	if replacement, ok := value.(*yamlv3.Node); ok {
+		return replacement, nil
+	}
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if node.Kind == yamlv3.MappingNode {
+			return mergeMapping(node, typed)
+		}
+	case []interface{}:
+		if node.Kind == yamlv3.SequenceNode {
+			return mergeSequence(node, typed)
+		}
+	default:
+		if node.Kind != yamlv3.MappingNode && node.Kind != yamlv3.SequenceNode && valuesEqual(nodeValue(node), value) {
+			return node, nil
+		}
+	}
+
+	// The value changed: encode it again, keeping the comments and, for scalars, the style
+	replacement, err := newNode(value)
+	if err != nil {
+		return nil, err
+	}
+	if replacement.Kind == yamlv3.ScalarNode && node.Kind == yamlv3.ScalarNode && replacement.Tag == node.Tag {
+		replacement.Style = node.Style
+	}
+	copyComments(replacement, node)
+	return replacement, nil
+}
+
+// mergeMapping merges m into a mapping node, keeping the order of the existing keys.
+func mergeMapping(node *yamlv3.Node, m map[string]interface{}) (*yamlv3.Node, error) {
+	merged := *node
+	merged.Content = nil
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		value, exists := m[key.Value]
+		if !exists || seen[key.Value] {
+			continue // Removed by cleaning
+		}
+		seen[key.Value] = true
+		child, err := mergeNode(node.Content[i+1], value)
+		if err != nil {
+			return nil, err
+		}
+		merged.Content = append(merged.Content, key, child)
+	}
+	for _, key := range sortedKeys(m) {
+		if seen[key] {
+			continue
+		}
+		child, err := newNode(m[key])
+		if err != nil {
+			return nil, err
+		}
+		merged.Content = append(merged.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: key}, child)
+	}
+	return &merged, nil
+}
+
+// mergeSequence merges items into a sequence node. Elements of named lists are matched by name,
+// lists of the same length by position, and other lists by finding unchanged elements in order.
+func mergeSequence(node *yamlv3.Node, items []interface{}) (*yamlv3.Node, error) {
+	merged := *node
+	merged.Content = nil
+
+	originals := make([]*yamlv3.Node, len(items))
+	switch {
+	case namedList(items):
+		byName := map[string]*yamlv3.Node{}
+		for _, element := range node.Content {
+			if name := mappingValue(element, "name"); name != nil && name.Kind == yamlv3.ScalarNode {
+				byName[name.Value] = element
+			}
+		}
+		for i, item := range items {
+			name, _ := listElementName(item)
+			originals[i] = byName[name]
+		}
+	case len(items) == len(node.Content):
+		copy(originals, node.Content)
+	default:
+		next := 0
+		for i, item := range items {
+			for j := next; j < len(node.Content); j++ {
+				if valuesEqual(nodeValue(node.Content[j]), item) {
+					originals[i], next = node.Content[j], j+1
+					break
+				}
+			}
+		}
+	}
+
+	for i, item := range items {
+		var child *yamlv3.Node
+		var err error
+		if originals[i] != nil {
+			child, err = mergeNode(originals[i], item)
+		} else {
+			child, err = newNode(item)
+		}
+		if err != nil {
+			return nil, err
+		}
+		merged.Content = append(merged.Content, child)
+	}
+	return &merged, nil
+}
+
+// copyComments copies the comments attached to from onto to.
+func copyComments(to, from *yamlv3.Node) {
+	to.HeadComment = from.HeadComment
+	to.LineComment = from.LineComment
+	to.FootComment = from.FootComment
+}
+
+// nodeIndentation detects the indentation of a document: the number of spaces per level and
+// whether sequences are written at the indentation of their parent key.
+func nodeIndentation(node *yamlv3.Node) (indent int, compactSeq bool) {
+	indent, compactSeq = defaultIndent, defaultCompactSeq
+	foundIndent, foundSeq := false, false
+	var walk func(node *yamlv3.Node)
+	walk = func(node *yamlv3.Node) {
+		if node.Kind == yamlv3.DocumentNode {
+			for _, child := range node.Content {
+				walk(child)
+			}
+			return
+		}
+		if node.Kind == yamlv3.SequenceNode {
+			for _, child := range node.Content {
+				walk(child)
+			}
+			return
+		}
+		if node.Kind != yamlv3.MappingNode || node.Style&yamlv3.FlowStyle != 0 {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Style&yamlv3.FlowStyle == 0 && value.Line > key.Line {
+				switch {
+				case value.Kind == yamlv3.MappingNode && !foundIndent && value.Column > key.Column:
+					indent, foundIndent = value.Column-key.Column, true
+				case value.Kind == yamlv3.SequenceNode && !foundSeq:
+					compactSeq, foundSeq = value.Column == key.Column, true
+				}
+			}
+			if foundIndent && foundSeq {
+				return
+			}
+			walk(value)
+		}
+	}
+	walk(node)
+	return indent, compactSeq
+}
+
+// documentWriter writes the cleaned objects: YAML documents separated by "---", or JSON.
+type documentWriter struct {
+	w             io.Writer
+	explicitStart bool         // Start the first document with "---" as well, like the input did
+	keyOrder      string       // keyOrderCanonical or keyOrderPreserve
+	format        string       // outputFormatYAML, outputFormatJSON or outputFormatJSONL
+	split         *SplitWriter // When set, every object goes to its own file instead of w
+	chart         *ChartWriter // When set, every object goes to a Helm chart instead of w
+	count         int
+}
+
+// write encodes node as the next document, with the given indentation.
+func (d *documentWriter) write(node *yamlv3.Node, indent int, compactSeq bool) error {
+	if d.count > 0 || d.explicitStart {
+		if _, err := io.WriteString(d.w, "---\n"); err != nil {
+			return err
+		}
+	}
+	d.count++
+
+	var buf bytes.Buffer
+	encoder := yamlv3.NewEncoder(&buf)
+	encoder.SetIndent(indent)
+	if compactSeq {
+		encoder.CompactSeqIndent()
+	}
+	if err := encoder.Encode(node); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	_, err := d.w.Write(buf.Bytes())
+	return err
+}
+
+// hasExplicitStart reports whether the input starts with a "---" document marker.
+func hasExplicitStart(reader *bufio.Reader) bool {
+	start, _ := reader.Peek(4)
+	return bytes.Equal(start, []byte("---\n")) || bytes.Equal(start, []byte("--- ")) ||
+		(len(start) == 3 && bytes.Equal(start, []byte("---")))
+}
+
+// writeUnchanged writes obj, which was not cleaned, as the next document. YAML documents are
+// written from source, keeping their comments and key order.
+func (d *documentWriter) writeUnchanged(obj *KubernetesObject, source *yamlv3.Node) error {
+	if source == nil || d.format != outputFormatYAML || d.split != nil || d.chart != nil {
+		return d.encodeObject(obj, source)
+	}
+	indent, compactSeq := nodeIndentation(source)
+	return d.write(source, indent, compactSeq)
+}
+
+// encodeObject writes obj as the next document, formatted like source (the document it was decoded from).
+func (d *documentWriter) encodeObject(obj *KubernetesObject, source *yamlv3.Node) error {
+	if d.split != nil {
+		var buf bytes.Buffer
+		file := &documentWriter{w: &buf, keyOrder: d.keyOrder, format: d.format}
+		if err := file.encodeObject(obj, source); err != nil {
+			return err
+		}
+		return d.split.write(obj, buf.Bytes())
+	}
+
+	node, err := objectNode(obj)
+	if err != nil {
+		return fmt.Errorf("error building YAML node: %w", err)
+	}
+	if d.keyOrder == keyOrderCanonical {
+		orderKeys(node, "", false)
+	}
+	if d.chart != nil {
+		d.chart.add(obj, node)
+		return nil
+	}
+	if d.format == outputFormatJSON || d.format == outputFormatJSONL {
+		return d.writeJSON(node)
+	}
+	indent, compactSeq := defaultIndent, defaultCompactSeq
+	if source != nil {
+		indent, compactSeq = nodeIndentation(source)
+		if source.Kind == yamlv3.DocumentNode && len(source.Content) == 1 && source.Content[0] == obj.node {
+			// Keep the comments attached to the document itself
+			document := *source
+			document.Content = []*yamlv3.Node{node}
+			node = &document
+		}
+	}
+	return d.write(node, indent, compactSeq)
+}
+
+// render returns obj encoded on its own the way d writes it, for the diffs of the report. Objects
+// that cannot be encoded render as empty text; writing them fails with the error.
+func (d *documentWriter) render(obj *KubernetesObject) string {
+	var buf bytes.Buffer
+	w := &documentWriter{w: &buf, keyOrder: d.keyOrder, format: d.format}
+	if err := w.encodeObject(obj, obj.node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCleanupManifestPreservesFormatting(t *testing.T) {
+	tests := []struct {
+		name     string
+		keyOrder string
+		input    string
+		expected string
+	}{
+		{
+			name:     "comments, key order and scalar styles",
+			keyOrder: keyOrderPreserve,
+			input: `# Storefront settings
+kind: ConfigMap # managed by platform
+apiVersion: v1
+metadata:
+  name: settings
+  namespace: shop
+  uid: 0b7e2f4c
+  labels:
+    team: 'storefront'
+data:
+  # The startup script
+  start.sh: |
+    #!/bin/sh
+    exec app --port "8080"
+  mode: "0755"
+`,
+			expected: `# Storefront settings
+kind: ConfigMap # managed by platform
+apiVersion: v1
+metadata:
+  name: settings
+  labels:
+    team: 'storefront'
+data:
+  # The startup script
+  start.sh: |
+    #!/bin/sh
+    exec app --port "8080"
+  mode: "0755"
+`,
+		},
+		{
+			name:     "document separators and indentation",
+			keyOrder: keyOrderPreserve,
+			input: `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+    name: web
+spec:
+    template:
+        spec:
+            containers:
+                - name: app   # main container
+                  image: nginx:1.25
+                  imagePullPolicy: IfNotPresent
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+    targetPort: 80
+`,
+			expected: `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+    name: web
+spec:
+    template:
+        spec:
+            containers:
+                - name: app # main container
+                  image: nginx:1.25
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80
+`,
+		},
+		{
+			name:     "canonical key order",
+			keyOrder: keyOrderCanonical,
+			input: `# Web frontend
+spec:
+  template:
+    spec:
+      containers:
+      - ports:
+        - protocol: UDP
+          containerPort: 53
+          name: dns
+        image: nginx:1.25
+        name: app # main container
+    metadata:
+      labels:
+        app: web
+  selector:
+    matchLabels:
+      app: web
+  replicas: 2
+metadata:
+  labels:
+    app: web
+  name: web
+kind: Deployment
+apiVersion: apps/v1
+`,
+			expected: `# Web frontend
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: app # main container
+        image: nginx:1.25
+        ports:
+        - name: dns
+          containerPort: 53
+          protocol: UDP
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := defaultCleanupOptions()
+			options.KeyOrder = tt.keyOrder
+			var output bytes.Buffer
+			if err := cleanupManifest(strings.NewReader(tt.input), &output, options); err != nil {
+				t.Fatalf("cleanupManifest returned error: %v", err)
+			}
+			if output.String() != tt.expected {
+				t.Errorf("Unexpected output.\nExpected:\n%s\nActual:\n%s", tt.expected, output.String())
+			}
+
+			// Cleaning the output again changes nothing
+			var again bytes.Buffer
+			if err := cleanupManifest(strings.NewReader(output.String()), &again, options); err != nil {
+				t.Fatalf("cleanupManifest returned error: %v", err)
+			}
+			if again.String() != output.String() {
+				t.Errorf("Cleanup is not idempotent.\nFirst:\n%s\nSecond:\n%s", output.String(), again.String())
+			}
+		})
+	}
+}
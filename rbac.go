@@ -0,0 +1,103 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strings"
+)
+
+// rbacAutoupdateAnnotation marks the bootstrap roles and bindings the API server reconciles on
+// every start. They exist in every cluster and must not be exported.
+const rbacAutoupdateAnnotation = "rbac.authorization.kubernetes.io/autoupdate"
+
+// generatedSecretSuffix matches the suffix of the Secrets created for a ServiceAccount: legacy
+// token Secrets (<account>-token-<random>) and OpenShift pull secrets (<account>-dockercfg-<random>).
+var generatedSecretSuffix = regexp.MustCompile(`^-(token|dockercfg)-[a-z0-9]{5}$`)
+
+// ServiceAccountCleaner cleans ServiceAccount-specific fields.
+type ServiceAccountCleaner struct {
+	genericCleaner ObjectCleaner
+}
+
+func (c *ServiceAccountCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
+	c.genericCleaner.Clean(obj, options)
+
+	// The generated Secrets are created again for the account in the target cluster
+	name, _ := obj.Metadata["name"].(string)
+	for _, key := range []string{"secrets", "imagePullSecrets"} {
+		references, ok := obj.Extra[key].([]interface{})
+		if !ok {
+			continue
+		}
+		var kept []interface{}
+		for i, reference := range references {
+			refMap, _ := reference.(map[string]interface{})
+			refName, _ := refMap["name"].(string)
+			if name != "" && isGeneratedSecretName(name, refName) {
+				obj.Changes.recordPath(listItemPath(appendKeyPath("", key), references, i), reference, "ServiceAccountCleaner:runtime")
+				continue
+			}
+			kept = append(kept, reference)
+		}
+		if len(kept) == 0 {
+			delete(obj.Extra, key) // The removed references are recorded
+		} else {
+			obj.Extra[key] = kept
+		}
+	}
+	if options.RemoveEmpty {
+		cleanupEmptyTopLevelFields(obj)
+	}
+}
+
+// isGeneratedSecretName reports whether secretName is the name of a Secret generated for the
+// ServiceAccount account.
+func isGeneratedSecretName(account, secretName string) bool {
+	suffix, ok := strings.CutPrefix(secretName, account)
+	return ok && generatedSecretSuffix.MatchString(suffix)
+}
+
+// RBACCleaner cleans Roles, ClusterRoles, RoleBindings and ClusterRoleBindings.
+type RBACCleaner struct {
+	genericCleaner ObjectCleaner
+}
+
+func (c *RBACCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
+	// Decide before cleaning: the generic cleaner may remove the annotations
+	name, _ := obj.Metadata["name"].(string)
+	annotations, _ := obj.Metadata["annotations"].(map[string]interface{})
+	switch {
+	case annotations[rbacAutoupdateAnnotation] == "true":
+		log.Printf("Dropping %s '%s': bootstrap object reconciled by the API server", obj.Kind, name)
+		obj.dropped = "RBACCleaner:runtime"
+		return
+	case options.SkipSystemRBAC && strings.HasPrefix(name, "system:"):
+		log.Printf("Dropping %s '%s': managed by the cluster", obj.Kind, name)
+		obj.dropped = "RBACCleaner:option"
+		return
+	}
+
+	c.genericCleaner.Clean(obj, options)
+
+	// The rules of aggregated ClusterRoles are filled in by the aggregation controller
+	if _, aggregated := obj.Extra["aggregationRule"]; aggregated && obj.Kind == "ClusterRole" {
+		removeField(obj, "rules", "RBACCleaner:runtime")
+	}
+
+	// ServiceAccount subjects of a RoleBinding default to the namespace of the binding; drop it
+	// along with the binding's own namespace so the binding works in any namespace
+	if _, kept := obj.Metadata["namespace"]; obj.Kind == "RoleBinding" && obj.namespace != "" && !kept {
+		subjects, _ := obj.Extra["subjects"].([]interface{})
+		for i, subject := range subjects {
+			subjectMap, ok := subject.(map[string]interface{})
+			if !ok || subjectMap["kind"] != "ServiceAccount" || subjectMap["namespace"] != obj.namespace {
+				continue
+			}
+			obj.Changes.recordPath(appendKeyPath(listItemPath(appendKeyPath("", "subjects"), subjects, i), "namespace"), obj.namespace, "RBACCleaner:option")
+			delete(subjectMap, "namespace")
+		}
+	}
+	if options.RemoveEmpty {
+		cleanupEmptyTopLevelFields(obj)
+	}
+}
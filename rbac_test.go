@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestIsGeneratedSecretName(t *testing.T) {
+	tests := []struct {
+		account    string
+		secretName string
+		expected   bool
+	}{
+		{account: "default", secretName: "default-token-4xk9z", expected: true},
+		{account: "builder", secretName: "builder-dockercfg-n8w2q", expected: true},
+		{account: "builder", secretName: "builder-token", expected: false},
+		{account: "builder", secretName: "builder-signing-key", expected: false},
+		{account: "builder", secretName: "other-token-4xk9z", expected: false},
+		{account: "build", secretName: "builder-token-4xk9z", expected: false},
+	}
+
+	for _, tt := range tests {
+		if actual := isGeneratedSecretName(tt.account, tt.secretName); actual != tt.expected {
+			t.Errorf("isGeneratedSecretName(%q, %q)\nExpected: %v\nActual: %v", tt.account, tt.secretName, tt.expected, actual)
+		}
+	}
+}
+
+func TestCleanupManifestSkipsSystemRBAC(t *testing.T) {
+	input := `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: system:controller:job-controller
+rules:
+- apiGroups: [batch]
+  resources: [jobs]
+  verbs: [get, list, watch]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: ci-builder
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: system:auth-delegator
+subjects:
+- {kind: ServiceAccount, name: builder, namespace: ci}
+`
+	tests := []struct {
+		name           string
+		skipSystemRBAC bool
+		expectedNames  []string
+	}{
+		{name: "disabled", skipSystemRBAC: false, expectedNames: []string{"system:controller:job-controller", "ci-builder"}},
+		{name: "enabled", skipSystemRBAC: true, expectedNames: []string{"ci-builder"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := defaultCleanupOptions()
+			options.SkipSystemRBAC = tt.skipSystemRBAC
+			options.Report = &CleanupReport{}
+			var output bytes.Buffer
+			if err := cleanupManifest(strings.NewReader(input), &output, options); err != nil {
+				t.Fatalf("cleanupManifest returned error: %v", err)
+			}
+			var names []string
+			for _, line := range strings.Split(output.String(), "\n") {
+				if name, ok := strings.CutPrefix(line, "  name: "); ok && !strings.HasPrefix(name, "system:auth") {
+					names = append(names, name)
+				}
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expectedNames, ",") {
+				t.Errorf("Unexpected objects.\nExpected: %v\nActual: %v", tt.expectedNames, names)
+			}
+			// Bindings to system roles are kept either way
+			if !strings.Contains(output.String(), "name: system:auth-delegator") {
+				t.Errorf("Expected the binding to keep its system roleRef, got:\n%s", output.String())
+			}
+			if tt.skipSystemRBAC {
+				removed := options.Report.Objects[0].Removed
+				if len(removed) != 1 || removed[0].Path != "" || removed[0].Reason != "RBACCleaner:option" {
+					t.Errorf("Expected the ClusterRole to be reported as dropped, got %+v", removed)
+				}
+			}
+		})
+	}
+}
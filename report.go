@@ -0,0 +1,88 @@
+package main
+
+import (
+	"reflect"
+)
+
+// CleanupReport collects what cleaning removed from every object. Set CleanupOptions.Report to
+// enable it; objects are appended in the order they are cleaned.
+type CleanupReport struct {
+	Objects []*ObjectReport `json:"objects"`
+}
+
+// ObjectReport lists the fields removed from one object, with the reason recorded for each.
+type ObjectReport struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Namespace  string   `json:"namespace,omitempty"`
+	Name       string   `json:"name,omitempty"`
+	Removed    []Change `json:"removed"`
+
+	original map[string]interface{} // The object before cleaning
+	cleaned  map[string]interface{} // The object after cleaning
+	before   string                 // The object before cleaning, as the output writer encodes it
+	after    string                 // The object after cleaning, as the output writer encodes it (empty when dropped)
+	rendered bool                   // Whether before and after are set
+}
+
+// begin starts the report of obj: it takes a snapshot of the object before it is cleaned and
+// attaches a ChangeRecorder unless one is attached already. With a writer, the snapshot also holds
+// the object as writer encodes it. It returns nil when reporting is disabled.
+func (r *CleanupReport) begin(obj *KubernetesObject, writer *documentWriter) *ObjectReport {
+	if r == nil {
+		return nil
+	}
+	if obj.Changes == nil {
+		obj.recordChanges()
+	}
+	report := &ObjectReport{
+		APIVersion: obj.APIVersion,
+		Kind:       obj.Kind,
+		original:   deepCopyValue(objectToMap(obj)).(map[string]interface{}),
+	}
+	report.Namespace, _ = obj.Metadata["namespace"].(string)
+	report.Name, _ = obj.Metadata["name"].(string)
+	if report.Name == "" {
+		report.Name, _ = obj.Metadata["generateName"].(string)
+	}
+	if writer != nil {
+		report.before, report.rendered = writer.render(obj), true
+	}
+	r.Objects = append(r.Objects, report)
+	return report
+}
+
+// finish completes the report with the cleaned object and the changes recorded while cleaning it.
+// writer is the writer passed to begin.
+func (o *ObjectReport) finish(obj *KubernetesObject, writer *documentWriter) {
+	if o == nil {
+		return
+	}
+	if obj.dropped != "" {
+		// The whole object is removed from the output
+		o.Removed = []Change{{Path: "", OldValue: o.original, Reason: obj.dropped}}
+		return
+	}
+	o.cleaned = deepCopyValue(objectToMap(obj)).(map[string]interface{})
+	if writer != nil {
+		o.after = writer.render(obj)
+	}
+	o.Removed = obj.Changes.Changes()
+	if o.Removed == nil {
+		o.Removed = []Change{}
+	}
+}
+
+// changed reports whether cleaning changed the object.
+func (o *ObjectReport) changed() bool {
+	return !reflect.DeepEqual(o.original, o.cleaned)
+}
+
+// key identifies the object in diffs, as apiVersion/kind[/namespace]/name of the input object.
+func (o *ObjectReport) key() string {
+	key := o.APIVersion + "/" + o.Kind
+	if o.Namespace != "" {
+		key += "/" + o.Namespace
+	}
+	return key + "/" + o.Name
+}
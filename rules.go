@@ -0,0 +1,566 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Field rule actions.
+const (
+	ruleActionRemove = "remove" // Delete the matched fields
+	ruleActionKeep   = "keep"   // Restore the matched fields if a built-in cleaner removed them
+	ruleActionSet    = "set"    // Set the matched fields to Value
+)
+
+// FieldRule is a user-defined removal/keep/set rule, applied after the built-in cleaners.
+//
+// Path uses a JSONPath-like syntax rooted at the object, e.g.
+// spec.template.spec.containers[*].env[?(@.name=="POD_IP")] or metadata.annotations['example.com/key'].
+type FieldRule struct {
+	Kind   string      `yaml:"kind,omitempty"`  // Kind to match; empty or "*" matches every kind
+	Path   string      `yaml:"path"`            // Path of the fields to act on
+	Action string      `yaml:"action"`          // "remove", "keep" or "set"
+	Value  interface{} `yaml:"value,omitempty"` // Value for the "set" action
+}
+
+// appliesTo reports whether the rule matches objects of the given kind.
+func (r *FieldRule) appliesTo(kind string) bool {
+	return r.Kind == "" || r.Kind == "*" || r.Kind == kind
+}
+
+// validate checks that the rule has a known action and a parseable path.
+func (r *FieldRule) validate() error {
+	switch r.Action {
+	case ruleActionRemove, ruleActionKeep:
+	case ruleActionSet:
+		if r.Value == nil {
+			return fmt.Errorf("rule %q: action %q requires a value", r.Path, r.Action)
+		}
+	default:
+		return fmt.Errorf("rule %q: unknown action %q: must be 'remove', 'keep' or 'set'", r.Path, r.Action)
+	}
+	if _, err := parseFieldPath(r.Path); err != nil {
+		return fmt.Errorf("rule %q: %w", r.Path, err)
+	}
+	return nil
+}
+
+// pathSegmentType identifies the kind of a parsed path segment.
+type pathSegmentType int
+
+const (
+	segmentField    pathSegmentType = iota // .name or ['name']
+	segmentIndex                           // [0]
+	segmentWildcard                        // [*] or .*
+	segmentFilter                          // [?(@.name=="value")]
+)
+
+// pathSegment is one step of a parsed field path.
+type pathSegment struct {
+	segmentType pathSegmentType
+	name        string      // segmentField
+	index       int         // segmentIndex
+	filterPath  []string    // segmentFilter: field path below @
+	filterOp    string      // segmentFilter: "==", "!=" or "" for existence
+	filterValue interface{} // segmentFilter: literal to compare with
+}
+
+// parseFieldPath parses a JSONPath-like field path into segments.
+func parseFieldPath(path string) ([]pathSegment, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	var segments []pathSegment
+
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+		case '[':
+			end, err := findClosingBracket(path, i)
+			if err != nil {
+				return nil, err
+			}
+			segment, err := parseBracketSegment(path[i+1 : end])
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, segment)
+			i = end + 1
+		default:
+			end := i
+			for end < len(path) && path[end] != '.' && path[end] != '[' {
+				end++
+			}
+			name := path[i:end]
+			if name == "*" {
+				segments = append(segments, pathSegment{segmentType: segmentWildcard})
+			} else {
+				segments = append(segments, pathSegment{segmentType: segmentField, name: name})
+			}
+			i = end
+		}
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return segments, nil
+}
+
+// findClosingBracket returns the index of the ']' matching the '[' at start, skipping quoted strings.
+func findClosingBracket(path string, start int) (int, error) {
+	var quote byte
+	for i := start + 1; i < len(path); i++ {
+		switch c := path[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated '[' at position %d", start)
+}
+
+// parseBracketSegment parses the content of a [...] segment.
+func parseBracketSegment(content string) (pathSegment, error) {
+	content = strings.TrimSpace(content)
+	switch {
+	case content == "*":
+		return pathSegment{segmentType: segmentWildcard}, nil
+	case isQuoted(content):
+		return pathSegment{segmentType: segmentField, name: content[1 : len(content)-1]}, nil
+	case strings.HasPrefix(content, "?(") && strings.HasSuffix(content, ")"):
+		return parseFilter(strings.TrimSpace(content[2 : len(content)-1]))
+	}
+	index, err := strconv.Atoi(content)
+	if err != nil || index < 0 {
+		return pathSegment{}, fmt.Errorf("invalid segment [%s]: expected an index, '*', a quoted key or a ?() filter", content)
+	}
+	return pathSegment{segmentType: segmentIndex, index: index}, nil
+}
+
+// parseFilter parses a filter expression such as @.name=="POD_IP".
+func parseFilter(expression string) (pathSegment, error) {
+	segment := pathSegment{segmentType: segmentFilter}
+	left := expression
+	if idx, op := findFilterOperator(expression); idx != -1 {
+		segment.filterOp = op
+		left = strings.TrimSpace(expression[:idx])
+		literal := strings.TrimSpace(expression[idx+len(op):])
+		if isQuoted(literal) {
+			segment.filterValue = literal[1 : len(literal)-1]
+		} else if err := yaml.Unmarshal([]byte(literal), &segment.filterValue); err != nil {
+			return pathSegment{}, fmt.Errorf("invalid filter value %q: %w", literal, err)
+		}
+	}
+	if !strings.HasPrefix(left, "@.") {
+		return pathSegment{}, fmt.Errorf("invalid filter %q: expected an expression like @.name==\"value\"", expression)
+	}
+	segment.filterPath = strings.Split(left[2:], ".")
+	return segment, nil
+}
+
+// findFilterOperator returns the index of the first == or != in expression outside quoted strings
+// and the operator, or -1 for an existence check.
+func findFilterOperator(expression string) (int, string) {
+	var quote byte
+	for i := 0; i+1 < len(expression); i++ {
+		switch c := expression[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case (c == '=' || c == '!') && expression[i+1] == '=':
+			return i, expression[i : i+2]
+		}
+	}
+	return -1, ""
+}
+
+func isQuoted(s string) bool {
+	return len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]
+}
+
+// matchesFilter reports whether a list element satisfies a filter segment.
+func (s *pathSegment) matchesFilter(item interface{}) bool {
+	var current interface{} = item
+	for _, name := range s.filterPath {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if current, ok = m[name]; !ok {
+			return s.filterOp == "!="
+		}
+	}
+	switch s.filterOp {
+	case "==":
+		return fmt.Sprint(current) == fmt.Sprint(s.filterValue)
+	case "!=":
+		return fmt.Sprint(current) != fmt.Sprint(s.filterValue)
+	default:
+		return true // Existence check
+	}
+}
+
+// removePath removes everything matched by segments below node. It returns the updated node and
+// false when the node itself should be removed from its parent (it became an empty list).
+func removePath(node interface{}, segments []pathSegment) (interface{}, bool) {
+	if len(segments) == 0 {
+		return node, true
+	}
+	segment, rest := segments[0], segments[1:]
+
+	switch value := node.(type) {
+	case map[string]interface{}:
+		var keys []string
+		switch segment.segmentType {
+		case segmentField:
+			keys = []string{segment.name}
+		case segmentWildcard:
+			for key := range value {
+				keys = append(keys, key)
+			}
+		}
+		for _, key := range keys {
+			child, exists := value[key]
+			if !exists {
+				continue
+			}
+			if len(rest) == 0 {
+				delete(value, key)
+				continue
+			}
+			if updated, keep := removePath(child, rest); keep {
+				value[key] = updated
+			} else {
+				delete(value, key) // Remove if list becomes empty
+			}
+		}
+		return value, true
+
+	case []interface{}:
+		cleaned := make([]interface{}, 0, len(value))
+		for i, item := range value {
+			matched := false
+			switch segment.segmentType {
+			case segmentIndex:
+				matched = i == segment.index
+			case segmentWildcard:
+				matched = true
+			case segmentFilter:
+				matched = segment.matchesFilter(item)
+			}
+			if !matched {
+				cleaned = append(cleaned, item)
+				continue
+			}
+			if len(rest) == 0 {
+				continue // Drop the matched element
+			}
+			updated, _ := removePath(item, rest)
+			cleaned = append(cleaned, updated)
+		}
+		return cleaned, len(cleaned) > 0
+	}
+	return node, true
+}
+
+// setPath sets value at every location matched by segments below node, creating missing maps for
+// field segments. It returns the updated node.
+func setPath(node interface{}, segments []pathSegment, value interface{}) interface{} {
+	if len(segments) == 0 {
+		return deepCopyValue(value)
+	}
+	segment, rest := segments[0], segments[1:]
+
+	switch current := node.(type) {
+	case map[string]interface{}:
+		switch segment.segmentType {
+		case segmentField:
+			current[segment.name] = setPath(current[segment.name], rest, value)
+		case segmentWildcard:
+			for key, child := range current {
+				current[key] = setPath(child, rest, value)
+			}
+		}
+		return current
+
+	case []interface{}:
+		matched := false
+		for i, item := range current {
+			switch segment.segmentType {
+			case segmentIndex:
+				if i != segment.index {
+					continue
+				}
+			case segmentFilter:
+				if !segment.matchesFilter(item) {
+					continue
+				}
+			case segmentField:
+				continue
+			}
+			matched = true
+			current[i] = setPath(item, rest, value)
+		}
+		// A missing element selected by a filter is appended when it is the target itself
+		if !matched && segment.segmentType == segmentFilter && len(rest) == 0 {
+			current = append(current, deepCopyValue(value))
+		}
+		return current
+
+	case nil:
+		if segment.segmentType == segmentField {
+			return map[string]interface{}{segment.name: setPath(nil, rest, value)}
+		}
+		if segment.segmentType == segmentFilter && len(rest) == 0 {
+			return []interface{}{deepCopyValue(value)}
+		}
+	}
+	return node
+}
+
+// keptField is a concrete location and value captured for a keep rule before cleaning.
+type keptField struct {
+	segments []pathSegment
+	value    interface{}
+}
+
+// collectPath returns every concrete location matched by segments below node. List elements that
+// carry a name are addressed by a name filter so they can be found again after the list changed.
+func collectPath(node interface{}, segments []pathSegment, prefix []pathSegment) []keptField {
+	if len(segments) == 0 {
+		return []keptField{{segments: prefix, value: deepCopyValue(node)}}
+	}
+	segment, rest := segments[0], segments[1:]
+	var found []keptField
+
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if segment.segmentType == segmentWildcard || (segment.segmentType == segmentField && segment.name == key) {
+				found = append(found, collectPath(child, rest, appendSegment(prefix, pathSegment{segmentType: segmentField, name: key}))...)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			switch segment.segmentType {
+			case segmentIndex:
+				if i != segment.index {
+					continue
+				}
+			case segmentFilter:
+				if !segment.matchesFilter(item) {
+					continue
+				}
+			case segmentField:
+				continue
+			}
+			location := pathSegment{segmentType: segmentIndex, index: i}
+			if m, ok := item.(map[string]interface{}); ok {
+				if name, ok := m["name"].(string); ok {
+					location = pathSegment{segmentType: segmentFilter, filterPath: []string{"name"}, filterOp: "==", filterValue: name}
+				}
+			}
+			found = append(found, collectPath(item, rest, appendSegment(prefix, location))...)
+		}
+	}
+	return found
+}
+
+// appendSegment returns a new slice so sibling locations never share a backing array.
+func appendSegment(prefix []pathSegment, segment pathSegment) []pathSegment {
+	return append(append(make([]pathSegment, 0, len(prefix)+1), prefix...), segment)
+}
+
+// deepCopyValue copies maps and slices so rule values are never shared between objects.
+func deepCopyValue(data interface{}) interface{} {
+	switch value := data.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			copied[k] = deepCopyValue(v)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, v := range value {
+			copied[i] = deepCopyValue(v)
+		}
+		return copied
+	default:
+		return data
+	}
+}
+
+// objectToMap converts a KubernetesObject into a generic tree rooted at the object.
+func objectToMap(obj *KubernetesObject) map[string]interface{} {
+	root := map[string]interface{}{}
+	for key, value := range obj.Extra {
+		root[key] = value
+	}
+	if obj.APIVersion != "" {
+		root["apiVersion"] = obj.APIVersion
+	}
+	if obj.Kind != "" {
+		root["kind"] = obj.Kind
+	}
+	if obj.Type != "" {
+		root["type"] = obj.Type
+	}
+	for key, value := range map[string]map[string]interface{}{
+		"metadata":   obj.Metadata,
+		"spec":       obj.Spec,
+		"status":     obj.Status,
+		"data":       obj.Data,
+		"stringData": obj.StringData,
+	} {
+		if value != nil {
+			root[key] = value
+		}
+	}
+	return root
+}
+
+// objectFromMap stores a generic tree produced by objectToMap back into obj. Items are kept as-is.
+func objectFromMap(obj *KubernetesObject, root map[string]interface{}) {
+	asMap := func(key string) map[string]interface{} {
+		m, _ := root[key].(map[string]interface{})
+		delete(root, key)
+		return m
+	}
+	asString := func(key string) string {
+		s, _ := root[key].(string)
+		delete(root, key)
+		return s
+	}
+	obj.APIVersion = asString("apiVersion")
+	obj.Kind = asString("kind")
+	obj.Type = asString("type")
+	obj.Metadata = asMap("metadata")
+	obj.Spec = asMap("spec")
+	obj.Status = asMap("status")
+	obj.Data = asMap("data")
+	obj.StringData = asMap("stringData")
+	obj.Extra = nil
+	if len(root) > 0 {
+		obj.Extra = root
+	}
+}
+
+// captureKeptFields records the values matched by keep rules before the built-in cleaners run.
+func captureKeptFields(obj *KubernetesObject, rules []FieldRule) []keptField {
+	var kept []keptField
+	var root map[string]interface{}
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Action != ruleActionKeep || !rule.appliesTo(obj.Kind) {
+			continue
+		}
+		segments, err := parseFieldPath(rule.Path)
+		if err != nil {
+			continue // Rules are validated when loaded
+		}
+		if root == nil {
+			root = objectToMap(obj)
+		}
+		kept = append(kept, collectPath(root, segments, nil)...)
+	}
+	return kept
+}
+
+// applyFieldRules applies remove/set rules to obj and restores the fields captured for keep rules.
+func applyFieldRules(obj *KubernetesObject, rules []FieldRule, kept []keptField) {
+	for i := range rules {
+		applyFieldRule(obj, &rules[i])
+	}
+	restoreKeptFields(obj, kept)
+}
+
+// applyFieldRule applies a single remove/set rule to obj. Keep rules are handled by restoreKeptFields.
+func applyFieldRule(obj *KubernetesObject, rule *FieldRule) {
+	if !rule.appliesTo(obj.Kind) || rule.Action == ruleActionKeep {
+		return
+	}
+	segments, err := parseFieldPath(rule.Path)
+	if err != nil {
+		return // Rules are validated when loaded
+	}
+	var root interface{} = objectToMap(obj)
+	var before map[string]interface{}
+	if obj.Changes != nil {
+		before = deepCopyValue(root).(map[string]interface{})
+	}
+	switch rule.Action {
+	case ruleActionRemove:
+		root, _ = removePath(root, segments)
+	case ruleActionSet:
+		root = setPath(root, segments, normalizeValue(rule.Value))
+	}
+	if before != nil {
+		// Rules work on a generic tree; record what they removed by comparing it with the snapshot
+		for _, removed := range removedFields(before, root, "") {
+			obj.Changes.recordPath(removed.Path, removed.OldValue, "rule:"+rule.Path)
+		}
+		obj.Changes.invalidate()
+	}
+	objectFromMap(obj, root.(map[string]interface{}))
+}
+
+// restoreKeptFields restores the fields captured by captureKeptFields that were removed or changed.
+func restoreKeptFields(obj *KubernetesObject, kept []keptField) {
+	if len(kept) == 0 {
+		return
+	}
+	var root interface{} = objectToMap(obj)
+	changed := false
+	for _, field := range kept {
+		current := collectPath(root, field.segments, nil)
+		if len(current) == 1 && reflect.DeepEqual(current[0].value, field.value) {
+			continue // Still present and unchanged
+		}
+		root = setPath(root, field.segments, field.value)
+		obj.Changes.forget(formatFieldPath(field.segments)) // Not removed after all
+		changed = true
+	}
+	if changed {
+		objectFromMap(obj, root.(map[string]interface{}))
+	}
+}
+
+// formatFieldPath formats parsed segments back into a field path, in the form used for changes.
+func formatFieldPath(segments []pathSegment) string {
+	path := ""
+	for _, segment := range segments {
+		switch segment.segmentType {
+		case segmentField:
+			path = appendKeyPath(path, segment.name)
+		case segmentIndex:
+			path += fmt.Sprintf("[%d]", segment.index)
+		case segmentWildcard:
+			path += "[*]"
+		case segmentFilter:
+			expression := "@." + strings.Join(segment.filterPath, ".")
+			if segment.filterOp != "" {
+				value := fmt.Sprint(segment.filterValue)
+				if _, isString := segment.filterValue.(string); isString {
+					value = strconv.Quote(value)
+				}
+				expression += segment.filterOp + value
+			}
+			path += "[?(" + expression + ")]"
+		}
+	}
+	return path
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func rulesTestDeployment() *KubernetesObject {
+	return &KubernetesObject{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Metadata: map[string]interface{}{
+			"name": "web",
+			"annotations": map[string]interface{}{
+				"example.com/build": "1234",
+				"example.com/owner": "storefront",
+			},
+		},
+		Spec: map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"dnsPolicy": "ClusterFirst",
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":  "web",
+							"image": "nginx",
+							"env": []interface{}{
+								map[string]interface{}{"name": "POD_IP", "value": "10.0.0.1"},
+								map[string]interface{}{"name": "MODE", "value": "prod"},
+							},
+						},
+						map[string]interface{}{
+							"name":  "sidecar",
+							"image": "envoy",
+							"env": []interface{}{
+								map[string]interface{}{"name": "POD_IP", "value": "10.0.0.1"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestParseFieldPath(t *testing.T) {
+	tests := []struct {
+		path          string
+		expectedTypes []pathSegmentType
+		expectError   bool
+	}{
+		{path: "spec.replicas", expectedTypes: []pathSegmentType{segmentField, segmentField}},
+		{path: "$.spec.containers[0].image", expectedTypes: []pathSegmentType{segmentField, segmentField, segmentIndex, segmentField}},
+		{path: `spec.containers[*].env[?(@.name=="POD_IP")]`, expectedTypes: []pathSegmentType{segmentField, segmentField, segmentWildcard, segmentField, segmentFilter}},
+		{path: "metadata.annotations['example.com/build']", expectedTypes: []pathSegmentType{segmentField, segmentField, segmentField}},
+		{path: "metadata.*", expectedTypes: []pathSegmentType{segmentField, segmentWildcard}},
+		{path: "spec.containers[", expectError: true},
+		{path: "spec.containers[abc]", expectError: true},
+		{path: "spec.containers[?(name==1)]", expectError: true},
+		{path: "", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			segments, err := parseFieldPath(tt.path)
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("Expected an error, got segments %+v", segments)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFieldPath returned error: %v", err)
+			}
+			var types []pathSegmentType
+			for _, segment := range segments {
+				types = append(types, segment.segmentType)
+			}
+			if !reflect.DeepEqual(types, tt.expectedTypes) {
+				t.Errorf("Expected segment types %v, got %v", tt.expectedTypes, types)
+			}
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   pathSegment
+	}{
+		{expression: `@.name=="POD_IP"`, expected: pathSegment{segmentType: segmentFilter, filterPath: []string{"name"}, filterOp: "==", filterValue: "POD_IP"}},
+		{expression: `@.value=='a==b'`, expected: pathSegment{segmentType: segmentFilter, filterPath: []string{"value"}, filterOp: "==", filterValue: "a==b"}},
+		{expression: `@.value != "a==b!=c"`, expected: pathSegment{segmentType: segmentFilter, filterPath: []string{"value"}, filterOp: "!=", filterValue: "a==b!=c"}},
+		{expression: `@.valueFrom.fieldRef`, expected: pathSegment{segmentType: segmentFilter, filterPath: []string{"valueFrom", "fieldRef"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			segment, err := parseFilter(tt.expression)
+			if err != nil {
+				t.Fatalf("parseFilter returned error: %v", err)
+			}
+			if !reflect.DeepEqual(tt.expected, segment) {
+				t.Errorf("Unexpected filter.\nExpected: %+v\nActual: %+v", tt.expected, segment)
+			}
+		})
+	}
+}
+
+func TestApplyFieldRules(t *testing.T) {
+	containersPath := []string{"spec", "template", "spec", "containers"}
+	getPath := func(obj *KubernetesObject, path ...string) interface{} {
+		var current interface{} = objectToMap(obj)
+		for _, key := range path {
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			current = m[key]
+		}
+		return current
+	}
+
+	tests := []struct {
+		name  string
+		rules []FieldRule
+		check func(t *testing.T, obj *KubernetesObject)
+	}{
+		{
+			name:  "removes filtered list elements in every container",
+			rules: []FieldRule{{Kind: "Deployment", Path: `spec.template.spec.containers[*].env[?(@.name=="POD_IP")]`, Action: ruleActionRemove}},
+			check: func(t *testing.T, obj *KubernetesObject) {
+				containers := getPath(obj, containersPath...).([]interface{})
+				web := containers[0].(map[string]interface{})
+				if env := web["env"].([]interface{}); len(env) != 1 || env[0].(map[string]interface{})["name"] != "MODE" {
+					t.Errorf("Expected only MODE to remain in web env, got %v", env)
+				}
+				sidecar := containers[1].(map[string]interface{})
+				if _, exists := sidecar["env"]; exists {
+					t.Errorf("Expected empty sidecar env to be removed, got %v", sidecar["env"])
+				}
+			},
+		},
+		{
+			name:  "removes by index and quoted key",
+			rules: []FieldRule{{Path: "spec.template.spec.containers[1]", Action: ruleActionRemove}, {Path: "metadata.annotations['example.com/build']", Action: ruleActionRemove}},
+			check: func(t *testing.T, obj *KubernetesObject) {
+				if containers := getPath(obj, containersPath...).([]interface{}); len(containers) != 1 {
+					t.Errorf("Expected one container to remain, got %d", len(containers))
+				}
+				if _, exists := getPath(obj, "metadata", "annotations").(map[string]interface{})["example.com/build"]; exists {
+					t.Errorf("Expected example.com/build annotation to be removed")
+				}
+			},
+		},
+		{
+			name:  "ignores rules for other kinds",
+			rules: []FieldRule{{Kind: "StatefulSet", Path: "metadata.annotations", Action: ruleActionRemove}},
+			check: func(t *testing.T, obj *KubernetesObject) {
+				if getPath(obj, "metadata", "annotations") == nil {
+					t.Errorf("Expected annotations to be kept for a Deployment")
+				}
+			},
+		},
+		{
+			name:  "sets values and creates missing maps",
+			rules: []FieldRule{{Path: "spec.template.metadata.labels.tier", Action: ruleActionSet, Value: "frontend"}, {Path: "spec.replicas", Action: ruleActionSet, Value: 3}},
+			check: func(t *testing.T, obj *KubernetesObject) {
+				if tier := getPath(obj, "spec", "template", "metadata", "labels", "tier"); tier != "frontend" {
+					t.Errorf("Expected tier label to be set, got %v", tier)
+				}
+				if replicas := getPath(obj, "spec", "replicas"); replicas != 3 {
+					t.Errorf("Expected replicas 3, got %v", replicas)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := rulesTestDeployment()
+			applyFieldRules(obj, tt.rules, captureKeptFields(obj, tt.rules))
+			tt.check(t, obj)
+		})
+	}
+}
+
+func TestKeepRuleRestoresCleanedFields(t *testing.T) {
+	obj := rulesTestDeployment()
+	options := defaultCleanupOptions()
+	options.FieldRules = []FieldRule{
+		{Kind: "Deployment", Path: "spec.template.spec.dnsPolicy", Action: ruleActionKeep},
+		{Kind: "Deployment", Path: "metadata.annotations['example.com/build']", Action: ruleActionRemove},
+	}
+
+	cleanupKubernetesObject(obj, options, NewObjectCleanerFactory())
+
+	podSpec := obj.Spec["template"].(map[string]interface{})["spec"].(map[string]interface{})
+	if podSpec["dnsPolicy"] != "ClusterFirst" {
+		t.Errorf("Expected keep rule to restore dnsPolicy, got %v", podSpec["dnsPolicy"])
+	}
+	annotations := obj.Metadata["annotations"].(map[string]interface{})
+	if _, exists := annotations["example.com/build"]; exists {
+		t.Errorf("Expected remove rule to delete example.com/build")
+	}
+	if annotations["example.com/owner"] != "storefront" {
+		t.Errorf("Expected unrelated annotations to be kept, got %v", annotations)
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// builtinSchemaJSON is klean's curated classification of built-in kinds, in OpenAPI v3 form. It is
+// written by hand, not generated from the Kubernetes spec; the file's info.description lists the
+// kinds it covers. Other kinds have no schema unless one is loaded.
+//
+//go:embed schemas/kubernetes-classification.json
+var builtinSchemaJSON []byte
+
+// builtinSchemas is the registry used when CleanupOptions.Schemas is nil.
+var builtinSchemas = mustLoadBuiltinSchemas()
+
+// openAPISchema is the subset of an OpenAPI v3 schema object klean needs for classification.
+type openAPISchema struct {
+	Ref        string                    `json:"$ref,omitempty"`
+	AllOf      []*openAPISchema          `json:"allOf,omitempty"`
+	Type       string                    `json:"type,omitempty"`
+	Properties map[string]*openAPISchema `json:"properties,omitempty"`
+	Items      *openAPISchema            `json:"items,omitempty"`
+	ReadOnly   bool                      `json:"readOnly,omitempty"`
+	Default    interface{}               `json:"default,omitempty"`
+	GVK        []groupVersionKind        `json:"x-kubernetes-group-version-kind,omitempty"`
+}
+
+type groupVersionKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// apiVersion returns the apiVersion string objects of this GVK carry.
+func (gvk groupVersionKind) apiVersion() string {
+	if gvk.Group == "" {
+		return gvk.Version
+	}
+	return gvk.Group + "/" + gvk.Version
+}
+
+// openAPIDocument is the top level of an OpenAPI v3 document.
+type openAPIDocument struct {
+	Components struct {
+		Schemas map[string]*openAPISchema `json:"schemas"`
+	} `json:"components"`
+}
+
+// SchemaRegistry classifies object fields as desired or runtime state using OpenAPI v3 schemas.
+type SchemaRegistry struct {
+	definitions map[string]*openAPISchema // Named schemas, for $ref resolution
+	byGVK       map[string]*openAPISchema // "apiVersion/Kind" -> object schema
+	byKind      map[string]*openAPISchema // Kind -> object schema, first registration wins
+}
+
+// NewSchemaRegistry returns a registry holding the bundled classification of built-in kinds.
+func NewSchemaRegistry() (*SchemaRegistry, error) {
+	registry := &SchemaRegistry{
+		definitions: map[string]*openAPISchema{},
+		byGVK:       map[string]*openAPISchema{},
+		byKind:      map[string]*openAPISchema{},
+	}
+	if err := registry.addOpenAPIDocument(builtinSchemaJSON); err != nil {
+		return nil, fmt.Errorf("error loading bundled classification: %w", err)
+	}
+	return registry, nil
+}
+
+func mustLoadBuiltinSchemas() *SchemaRegistry {
+	registry, err := NewSchemaRegistry()
+	if err != nil {
+		panic(err)
+	}
+	return registry
+}
+
+// addOpenAPIDocument registers every schema of an OpenAPI v3 document (JSON or YAML).
+func (r *SchemaRegistry) addOpenAPIDocument(data []byte) error {
+	var document openAPIDocument
+	if err := decodeSchemaData(data, &document); err != nil {
+		return err
+	}
+	if len(document.Components.Schemas) == 0 {
+		return fmt.Errorf("no components.schemas found")
+	}
+	for name, schema := range document.Components.Schemas {
+		r.definitions[name] = schema
+		for _, gvk := range schema.GVK {
+			r.register(gvk, schema)
+		}
+	}
+	return nil
+}
+
+// register makes an object schema available for lookups by GVK and by Kind.
+func (r *SchemaRegistry) register(gvk groupVersionKind, schema *openAPISchema) {
+	r.byGVK[gvk.apiVersion()+"/"+gvk.Kind] = schema
+	if _, exists := r.byKind[gvk.Kind]; !exists {
+		r.byKind[gvk.Kind] = schema
+	}
+}
+
+// LoadFile registers schemas from a CustomResourceDefinition manifest (possibly several documents)
+// or an OpenAPI v3 document, e.g. the output of `kubectl get --raw /openapi/v3/apis/<group>/<version>`.
+func (r *SchemaRegistry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading schema file '%s': %w", path, err)
+	}
+
+	// Try CRDs first: they are the common case and may come as a multi-document stream
+	loaded, err := r.addCRDs(data)
+	if err != nil {
+		return fmt.Errorf("schema file '%s': %w", path, err)
+	}
+	if loaded == 0 {
+		if err := r.addOpenAPIDocument(data); err != nil {
+			return fmt.Errorf("schema file '%s': expected CustomResourceDefinitions or an OpenAPI v3 document: %w", path, err)
+		}
+	}
+	log.Printf("Loaded schemas from: %s", path)
+	return nil
+}
+
+// addCRDs registers the schema of every served version of the CRDs in data and returns their count.
+func (r *SchemaRegistry) addCRDs(data []byte) (int, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	loaded := 0
+	for {
+		var obj KubernetesObject
+		if err := decoder.Decode(&obj); err == io.EOF {
+			return loaded, nil
+		} else if err != nil {
+			return loaded, err
+		}
+		normalizeObject(&obj)
+		if obj.Kind != "CustomResourceDefinition" || obj.Spec == nil {
+			continue
+		}
+
+		group, _ := obj.Spec["group"].(string)
+		names, _ := obj.Spec["names"].(map[string]interface{})
+		kind, _ := names["kind"].(string)
+		versions, _ := obj.Spec["versions"].([]interface{})
+		for _, v := range versions {
+			version, _ := v.(map[string]interface{})
+			versionName, _ := version["name"].(string)
+			schemaHolder, _ := version["schema"].(map[string]interface{})
+			rawSchema, ok := schemaHolder["openAPIV3Schema"]
+			if !ok || kind == "" || versionName == "" {
+				continue
+			}
+
+			schema := &openAPISchema{}
+			if err := convertViaJSON(rawSchema, schema); err != nil {
+				return loaded, fmt.Errorf("CRD %s version %s: %w", kind, versionName, err)
+			}
+			// With the status subresource, status can only be written by controllers
+			if subresources, ok := version["subresources"].(map[string]interface{}); ok {
+				if _, hasStatus := subresources["status"]; hasStatus {
+					if schema.Properties == nil {
+						schema.Properties = map[string]*openAPISchema{}
+					}
+					status := schema.Properties["status"]
+					if status == nil {
+						status = &openAPISchema{Type: "object"}
+						schema.Properties["status"] = status
+					}
+					status.ReadOnly = true
+				}
+			}
+			// CRD schemas do not describe metadata; reuse the built-in ObjectMeta schema
+			if schema.Properties == nil {
+				schema.Properties = map[string]*openAPISchema{}
+			}
+			if metadata := schema.Properties["metadata"]; metadata == nil || len(metadata.Properties) == 0 {
+				schema.Properties["metadata"] = &openAPISchema{Ref: "#/components/schemas/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"}
+			}
+
+			gvk := groupVersionKind{Group: group, Version: versionName, Kind: kind}
+			r.definitions[gvk.apiVersion()+"/"+kind] = schema
+			r.register(gvk, schema)
+			loaded++
+		}
+	}
+}
+
+// decodeSchemaData decodes JSON or YAML schema data into target.
+func decodeSchemaData(data []byte, target interface{}) error {
+	if err := json.Unmarshal(data, target); err == nil {
+		return nil
+	}
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	return convertViaJSON(normalizeValue(raw), target)
+}
+
+// convertViaJSON converts a generic tree into target through its JSON representation.
+func convertViaJSON(data interface{}, target interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, target)
+}
+
+// resolve follows $ref and single-element allOf wrappers to the referenced schema.
+func (r *SchemaRegistry) resolve(schema *openAPISchema) *openAPISchema {
+	for depth := 0; schema != nil && depth < 16; depth++ {
+		switch {
+		case schema.Ref != "":
+			target := r.definitions[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+			if target == nil {
+				return schema
+			}
+			// A readOnly/default on the referencing property applies to the target as well
+			if schema.ReadOnly || schema.Default != nil {
+				merged := *target
+				merged.ReadOnly = merged.ReadOnly || schema.ReadOnly
+				if schema.Default != nil {
+					merged.Default = schema.Default
+				}
+				target = &merged
+			}
+			schema = target
+		case len(schema.AllOf) == 1 && schema.Properties == nil:
+			inner := *schema.AllOf[0]
+			inner.ReadOnly = inner.ReadOnly || schema.ReadOnly
+			if schema.Default != nil {
+				inner.Default = schema.Default
+			}
+			schema = &inner
+		default:
+			return schema
+		}
+	}
+	return schema
+}
+
+// lookup returns the object schema for apiVersion/kind, falling back to any version of the kind.
+func (r *SchemaRegistry) lookup(apiVersion, kind string) *openAPISchema {
+	if schema, ok := r.byGVK[apiVersion+"/"+kind]; ok {
+		return schema
+	}
+	return r.byKind[kind]
+}
+
+// property returns the resolved schema of a named property, or nil.
+func (r *SchemaRegistry) property(schema *openAPISchema, name string) *openAPISchema {
+	schema = r.resolve(schema)
+	if schema == nil || schema.Properties == nil {
+		return nil
+	}
+	return r.resolve(schema.Properties[name])
+}
+
+// isRuntimeValue reports whether a field with the given schema holds runtime state: it is readOnly,
+// or it still carries the default of its schema. Only loaded schemas (CRDs) have defaults; those of
+// built-in kinds come from kindSpecDefaults.
+func isRuntimeValue(schema *openAPISchema, value interface{}) bool {
+	if schema.ReadOnly {
+		return true
+	}
+	return schema.Default != nil && valuesEqual(schema.Default, value)
+}
+
+// valuesEqual compares two decoded values, treating numbers of different Go types as equal.
+func valuesEqual(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	var normalizedA, normalizedB interface{}
+	if convertViaJSON(a, &normalizedA) != nil || convertViaJSON(b, &normalizedB) != nil {
+		return false
+	}
+	return reflect.DeepEqual(normalizedA, normalizedB)
+}
+
+// stateFields classifies the fields present in obj, using dot notation: true for desired state,
+// false for runtime state. It covers readOnly fields (status, metadata.uid, ...) and every spec field.
+// It returns nil for kinds without a schema.
+func (r *SchemaRegistry) stateFields(obj *KubernetesObject) map[string]bool {
+	schema := r.lookup(obj.APIVersion, obj.Kind)
+	if schema == nil {
+		return nil
+	}
+
+	defaulted := specDefaults(obj.Kind, obj.Spec)
+	fields := map[string]bool{}
+	for key, value := range objectToMap(obj) {
+		property := r.property(schema, key)
+		if property == nil {
+			continue
+		}
+		if property.ReadOnly {
+			fields[key] = false // e.g. status
+			continue
+		}
+		children, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for childKey, childValue := range children {
+			childProperty := r.property(property, childKey)
+			if childProperty == nil {
+				continue
+			}
+			switch key {
+			case "metadata":
+				if childProperty.ReadOnly {
+					fields[key+"."+childKey] = false // Only runtime metadata is classified
+				}
+			case "spec":
+				fields[key+"."+childKey] = !isRuntimeValue(childProperty, childValue) && !defaulted[childKey]
+			}
+		}
+	}
+	return fields
+}
+
+// podSpecFieldState classifies a pod spec field by name, independently of its value.
+func (r *SchemaRegistry) podSpecFieldState(field string) (isDesired bool, exists bool) {
+	property := r.property(r.definitions["io.k8s.api.core.v1.PodSpec"], field)
+	if property == nil {
+		return false, false
+	}
+	return !property.ReadOnly, true
+}
+
+// schemasFor returns the registry configured in options, or the bundled one.
+func schemasFor(options *CleanupOptions) *SchemaRegistry {
+	if options != nil && options.Schemas != nil {
+		return options.Schemas
+	}
+	return builtinSchemas
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSchemaRegistryStateFields(t *testing.T) {
+	schemas, err := NewSchemaRegistry()
+	if err != nil {
+		t.Fatalf("NewSchemaRegistry returned error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		obj      *KubernetesObject
+		expected map[string]bool
+	}{
+		{
+			name: "Deployment",
+			obj: &KubernetesObject{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Metadata:   map[string]interface{}{"name": "web", "uid": "1234", "generation": 2},
+				Spec: map[string]interface{}{
+					"replicas":             3,
+					"revisionHistoryLimit": 10, // Server default
+					"minReadySeconds":      5,
+					"strategy": map[string]interface{}{ // Server default, listed field by field
+						"type":          "RollingUpdate",
+						"rollingUpdate": map[string]interface{}{"maxSurge": "25%", "maxUnavailable": "25%"},
+					},
+				},
+				Status: map[string]interface{}{"replicas": 3},
+			},
+			expected: map[string]bool{
+				"metadata.uid":              false,
+				"metadata.generation":       false,
+				"spec.replicas":             true,
+				"spec.revisionHistoryLimit": false,
+				"spec.minReadySeconds":      true,
+				"spec.strategy":             false,
+				"status":                    false,
+			},
+		},
+		{
+			name: "Service",
+			obj: &KubernetesObject{
+				APIVersion: "v1",
+				Kind:       "Service",
+				Spec: map[string]interface{}{
+					"clusterIP":       "10.0.0.1",
+					"sessionAffinity": "ClientIP", // Not the default
+					"ports":           []interface{}{},
+				},
+			},
+			expected: map[string]bool{
+				"spec.clusterIP":       false,
+				"spec.sessionAffinity": true,
+				"spec.ports":           true,
+			},
+		},
+		{
+			name:     "unknown kind",
+			obj:      &KubernetesObject{APIVersion: "example.com/v1", Kind: "Widget", Spec: map[string]interface{}{"size": 1}},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := schemas.stateFields(tt.obj); !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("Expected state fields %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSchemaRegistryLoadCRD(t *testing.T) {
+	crd := `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+    plural: widgets
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    subresources:
+      status: {}
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              size:
+                type: integer
+                default: 1
+              color:
+                type: string
+              assignedNode:
+                type: string
+                readOnly: true
+`
+	path := filepath.Join(t.TempDir(), "widget-crd.yaml")
+	if err := os.WriteFile(path, []byte(crd), 0o644); err != nil {
+		t.Fatalf("failed to write CRD: %v", err)
+	}
+	schemas, err := NewSchemaRegistry()
+	if err != nil {
+		t.Fatalf("NewSchemaRegistry returned error: %v", err)
+	}
+	if err := schemas.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile returned error: %v", err)
+	}
+
+	obj := &KubernetesObject{
+		APIVersion: "example.com/v1",
+		Kind:       "Widget",
+		Metadata:   map[string]interface{}{"name": "blue", "resourceVersion": "42"},
+		Spec:       map[string]interface{}{"size": 1, "color": "blue", "assignedNode": "node-1"},
+		Status:     map[string]interface{}{"ready": true},
+	}
+	expected := map[string]bool{
+		"metadata.resourceVersion": false,
+		"spec.size":                false,
+		"spec.color":               true,
+		"spec.assignedNode":        false,
+		"status":                   false,
+	}
+	if actual := schemas.stateFields(obj); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected state fields %v, got %v", expected, actual)
+	}
+
+	// Desired mode removes the runtime fields the CRD schema describes
+	options := &CleanupOptions{PreserveResourceState: true, ResourceStateMode: "Desired", Schemas: schemas}
+	cleanupKubernetesObject(obj, options, NewObjectCleanerFactory())
+	if !reflect.DeepEqual(obj.Spec, map[string]interface{}{"color": "blue"}) {
+		t.Errorf("Expected only desired spec fields to remain, got %v", obj.Spec)
+	}
+	if obj.Status != nil {
+		t.Errorf("Expected status to be removed, got %v", obj.Status)
+	}
+}
+
+func TestRuntimeModeAcrossKinds(t *testing.T) {
+	obj := &KubernetesObject{
+		APIVersion: "apps/v1",
+		Kind:       "StatefulSet",
+		Metadata:   map[string]interface{}{"name": "db", "generation": 4},
+		Spec:       map[string]interface{}{"replicas": 3, "serviceName": "db"},
+		Status:     map[string]interface{}{"readyReplicas": 3},
+	}
+	options := &CleanupOptions{RemoveStatus: true, PreserveResourceState: true, ResourceStateMode: "Runtime"}
+	cleanupKubernetesObject(obj, options, NewObjectCleanerFactory())
+
+	if len(obj.Spec) != 0 {
+		t.Errorf("Expected desired spec fields to be removed in Runtime mode, got %v", obj.Spec)
+	}
+	if obj.Status == nil {
+		t.Errorf("Expected status to be kept in Runtime mode")
+	}
+	if _, ok := obj.Metadata["generation"]; !ok {
+		t.Errorf("Expected metadata.generation to be kept in Runtime mode")
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// defaultSplitTemplate names the files written by --split-dir. Cluster-scoped objects have no
+// namespace, so they are written at the top of the split directory.
+const defaultSplitTemplate = "{{.Namespace}}/{{.Kind | lower}}-{{.Name}}.yaml"
+
+// SplitWriter writes every cleaned object to its own file below Dir, named by a template.
+type SplitWriter struct {
+	Dir   string
+	Files []SplitFile // The files written, in order
+
+	template *template.Template
+	used     map[string]bool // Paths written in this run, to resolve collisions
+}
+
+// SplitFile describes a file written by a SplitWriter.
+type SplitFile struct {
+	Path             string            // Relative to the split directory, with forward slashes
+	Namespace        string            // Namespace of the object before cleaning
+	NamespaceRemoved bool              // Whether cleaning removed the namespace
+	Labels           map[string]string // metadata.labels of the cleaned object
+}
+
+// splitFileData is the data available to split file name templates.
+type splitFileData struct {
+	APIVersion string
+	Group      string // Empty for the core group
+	Version    string
+	Kind       string
+	Namespace  string // The namespace before cleaning; empty for cluster-scoped objects
+	Name       string // metadata.name, or metadata.generateName for generated names
+}
+
+// NewSplitWriter returns a SplitWriter writing below dir with the given file name template.
+func NewSplitWriter(dir, fileTemplate string) (*SplitWriter, error) {
+	parsed, err := template.New("split").Funcs(template.FuncMap{
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+	}).Option("missingkey=error").Parse(fileTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid split template %q: %w", fileTemplate, err)
+	}
+	return &SplitWriter{Dir: dir, template: parsed, used: map[string]bool{}}, nil
+}
+
+// path returns the file path of obj relative to the split directory. Paths never leave the
+// directory; empty segments (e.g. the namespace of a cluster-scoped object) are dropped.
+func (s *SplitWriter) path(obj *KubernetesObject) (string, error) {
+	data := splitFileData{APIVersion: obj.APIVersion, Kind: obj.Kind, Namespace: obj.namespace}
+	data.Group, data.Version, _ = strings.Cut(obj.APIVersion, "/")
+	if data.Version == "" {
+		data.Group, data.Version = "", obj.APIVersion
+	}
+	data.Name, _ = obj.Metadata["name"].(string)
+	if data.Name == "" {
+		data.Name, _ = obj.Metadata["generateName"].(string)
+		data.Name = strings.TrimSuffix(data.Name, "-")
+	}
+
+	var buf bytes.Buffer
+	if err := s.template.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error naming file for %s %s: %w", obj.Kind, data.Name, err)
+	}
+	path := strings.TrimPrefix(filepath.ToSlash(filepath.Clean("/"+buf.String())), "/")
+	if path == "" || strings.HasSuffix(buf.String(), "/") {
+		return "", fmt.Errorf("split template produced an invalid file name %q for %s %s", buf.String(), obj.Kind, data.Name)
+	}
+	return path, nil
+}
+
+// write writes the encoded obj to its file. When an earlier object of this run was written to the
+// same path, a numeric suffix is added before the extension.
+func (s *SplitWriter) write(obj *KubernetesObject, data []byte) error {
+	path, err := s.path(obj)
+	if err != nil {
+		return err
+	}
+	if s.used[path] {
+		ext := filepath.Ext(path)
+		base := strings.TrimSuffix(path, ext)
+		for i := 2; s.used[path]; i++ {
+			path = fmt.Sprintf("%s-%d%s", base, i, ext)
+		}
+	}
+	s.used[path] = true
+
+	if err := writeMirroredFile(filepath.Join(s.Dir, filepath.FromSlash(path)), data); err != nil {
+		return err
+	}
+	_, kept := obj.Metadata["namespace"]
+	file := SplitFile{Path: path, Namespace: obj.namespace, NamespaceRemoved: obj.namespace != "" && !kept, Labels: map[string]string{}}
+	if labels, ok := obj.Metadata["labels"].(map[string]interface{}); ok {
+		for key, value := range labels {
+			file.Labels[key] = fmt.Sprint(value)
+		}
+	}
+	s.Files = append(s.Files, file)
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitWriterPaths(t *testing.T) {
+	newObject := func(kind, namespace, name string) *KubernetesObject {
+		return &KubernetesObject{
+			APIVersion: "rbac.authorization.k8s.io/v1",
+			Kind:       kind,
+			Metadata:   map[string]interface{}{"name": name},
+			namespace:  namespace,
+		}
+	}
+	tests := []struct {
+		name     string
+		template string
+		obj      *KubernetesObject
+		expected string
+	}{
+		{name: "namespaced", template: defaultSplitTemplate, obj: newObject("Role", "shop", "reader"), expected: "shop/role-reader.yaml"},
+		{name: "cluster-scoped", template: defaultSplitTemplate, obj: newObject("ClusterRole", "", "reader"), expected: "clusterrole-reader.yaml"},
+		{name: "group and version", template: "{{.Group}}/{{.Version}}/{{.Kind}}.yaml", obj: newObject("Role", "shop", "reader"), expected: "rbac.authorization.k8s.io/v1/Role.yaml"},
+		{name: "cannot leave the directory", template: "../../{{.Name}}.yaml", obj: newObject("Role", "shop", "reader"), expected: "reader.yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			split, err := NewSplitWriter(t.TempDir(), tt.template)
+			if err != nil {
+				t.Fatalf("NewSplitWriter returned error: %v", err)
+			}
+			actual, err := split.path(tt.obj)
+			if err != nil {
+				t.Fatalf("path returned error: %v", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("Unexpected path.\nExpected: %s\nActual: %s", tt.expected, actual)
+			}
+		})
+	}
+
+	if _, err := NewSplitWriter(t.TempDir(), "{{.Kind | plural}}"); err == nil {
+		t.Errorf("Expected an error for an unknown template function")
+	}
+}
+
+func TestCleanupManifestSplit(t *testing.T) {
+	input := `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: settings
+    namespace: shop
+  data:
+    mode: production
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: settings
+    namespace: shop
+  data:
+    mode: staging
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: shop
+`
+	dir := t.TempDir()
+	split, err := NewSplitWriter(dir, defaultSplitTemplate)
+	if err != nil {
+		t.Fatalf("NewSplitWriter returned error: %v", err)
+	}
+	options := defaultCleanupOptions()
+	options.Split = split
+	var output bytes.Buffer
+	if err := cleanupManifest(strings.NewReader(input), &output, options); err != nil {
+		t.Fatalf("cleanupManifest returned error: %v", err)
+	}
+	if output.Len() != 0 {
+		t.Errorf("Expected no output besides the files, got:\n%s", output.String())
+	}
+
+	expected := map[string]string{
+		"shop/configmap-settings.yaml":   "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  mode: production\n",
+		"shop/configmap-settings-2.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  mode: staging\n",
+		"namespace-shop.yaml":            "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: shop\n",
+	}
+	for path, content := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Errorf("Expected file %s: %v", path, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("Unexpected content of %s.\nExpected:\n%s\nActual:\n%s", path, content, data)
+		}
+	}
+	if len(split.Files) != len(expected) {
+		t.Errorf("Expected %d files, got %v", len(expected), split.Files)
+	}
+}
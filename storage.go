@@ -0,0 +1,97 @@
+package main
+
+// Volume binding modes, see CleanupOptions.VolumeBinding.
+const (
+	volumeBindingUnbind   = "unbind"   // Drop the binding: claims are provisioned again in the target cluster
+	volumeBindingPreserve = "preserve" // Keep claims bound to the same (statically provisioned) volumes
+)
+
+// volumeBindingAnnotations are the annotations the PersistentVolume controller and the scheduler
+// set while binding claims and volumes.
+var volumeBindingAnnotations = []string{
+	"pv.kubernetes.io/bind-completed",
+	"pv.kubernetes.io/bound-by-controller",
+	"volume.beta.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/storage-provisioner",
+	"volume.kubernetes.io/selected-node",
+}
+
+// claimRefRuntimeFields are the fields of a PersistentVolume's claimRef that identify the claim
+// object in the source cluster rather than the claim by name.
+var claimRefRuntimeFields = []string{"uid", "resourceVersion"}
+
+// PersistentVolumeClaimCleaner cleans PersistentVolumeClaim-specific fields.
+type PersistentVolumeClaimCleaner struct {
+	genericCleaner ObjectCleaner
+}
+
+func (c *PersistentVolumeClaimCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
+	c.genericCleaner.Clean(obj, options)
+
+	cleanVolumeMetadata(obj, "kubernetes.io/pvc-protection", "PersistentVolumeClaimCleaner")
+	if obj.Spec != nil && options.VolumeBinding == volumeBindingUnbind {
+		// Without volumeName the claim is provisioned again from its storage class
+		obj.Changes.remove(obj.Spec, "volumeName", "PersistentVolumeClaimCleaner:option")
+	}
+	if options.RemoveEmpty {
+		cleanupEmptyTopLevelFields(obj)
+	}
+}
+
+// PersistentVolumeCleaner cleans PersistentVolume-specific fields.
+type PersistentVolumeCleaner struct {
+	genericCleaner ObjectCleaner
+}
+
+func (c *PersistentVolumeCleaner) Clean(obj *KubernetesObject, options *CleanupOptions) {
+	c.genericCleaner.Clean(obj, options)
+
+	cleanVolumeMetadata(obj, "kubernetes.io/pv-protection", "PersistentVolumeCleaner")
+	if obj.Spec != nil {
+		if options.VolumeBinding == volumeBindingUnbind {
+			// An unbound volume is Available to any matching claim
+			obj.Changes.remove(obj.Spec, "claimRef", "PersistentVolumeCleaner:option")
+		} else if claimRef, ok := obj.Spec["claimRef"].(map[string]interface{}); ok {
+			// The claim is matched by namespace and name; a stale uid would keep the volume Released
+			for _, field := range claimRefRuntimeFields {
+				obj.Changes.remove(claimRef, field, "PersistentVolumeCleaner:runtime")
+			}
+		}
+	}
+	if options.RemoveEmpty {
+		cleanupEmptyTopLevelFields(obj)
+	}
+}
+
+// cleanVolumeMetadata removes the binding annotations and the protection finalizer of a claim or
+// volume. The finalizer is added again by the API server, so it goes even when finalizers are kept.
+func cleanVolumeMetadata(obj *KubernetesObject, protectionFinalizer, cleaner string) {
+	if obj.Metadata == nil {
+		return
+	}
+	if annotations, ok := obj.Metadata["annotations"].(map[string]interface{}); ok {
+		for _, key := range volumeBindingAnnotations {
+			obj.Changes.remove(annotations, key, cleaner+":runtime")
+		}
+		if len(annotations) == 0 {
+			obj.Changes.remove(obj.Metadata, "annotations", cleaner+":empty")
+		}
+	}
+	finalizers, ok := obj.Metadata["finalizers"].([]interface{})
+	if !ok {
+		return
+	}
+	var kept []interface{}
+	for i, finalizer := range finalizers {
+		if finalizer == protectionFinalizer {
+			obj.Changes.recordItem(obj.Metadata, "finalizers", finalizers, i, cleaner+":runtime")
+		} else {
+			kept = append(kept, finalizer)
+		}
+	}
+	if len(kept) == 0 {
+		delete(obj.Metadata, "finalizers") // The removed finalizers are recorded
+	} else {
+		obj.Metadata["finalizers"] = kept
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestVolumeCleaners(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		options  func(options *CleanupOptions)
+		expected string
+	}{
+		{
+			name: "preserves a static binding",
+			input: `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  annotations: {pv.kubernetes.io/bind-completed: "yes"}
+spec:
+  storageClassName: ""
+  volumeName: nfs-data
+`,
+			options: func(options *CleanupOptions) { options.VolumeBinding = volumeBindingPreserve },
+			expected: `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata: {name: data}
+spec:
+  storageClassName: ""
+  volumeName: nfs-data
+`,
+		},
+		{
+			name: "keeps the claim of a statically bound volume by name",
+			input: `
+apiVersion: v1
+kind: PersistentVolume
+metadata:
+  name: nfs-data
+  annotations: {pv.kubernetes.io/bound-by-controller: "yes"}
+spec:
+  claimRef: {kind: PersistentVolumeClaim, namespace: shop, name: data, uid: 8e0f6c1d, resourceVersion: "512221"}
+  nfs: {server: nfs.example.com, path: /exports/data}
+`,
+			options: func(options *CleanupOptions) { options.VolumeBinding = volumeBindingPreserve },
+			expected: `
+apiVersion: v1
+kind: PersistentVolume
+metadata: {name: nfs-data}
+spec:
+  claimRef: {kind: PersistentVolumeClaim, namespace: shop, name: data}
+  nfs: {server: nfs.example.com, path: /exports/data}
+`,
+		},
+		{
+			name: "removes the protection finalizer when finalizers are kept",
+			input: `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  finalizers: [kubernetes.io/pvc-protection, example.com/backup]
+spec:
+  volumeName: pvc-8e0f6c1d
+`,
+			options: func(options *CleanupOptions) { options.CleanupFinalizers = false },
+			expected: `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  finalizers: [example.com/backup]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var obj, expected KubernetesObject
+			if err := yaml.Unmarshal([]byte(tt.input), &obj); err != nil {
+				t.Fatalf("failed to parse input: %v", err)
+			}
+			if err := yaml.Unmarshal([]byte(tt.expected), &expected); err != nil {
+				t.Fatalf("failed to parse expected output: %v", err)
+			}
+			normalizeObject(&obj)
+			normalizeObject(&expected)
+
+			options := defaultCleanupOptions()
+			if tt.options != nil {
+				tt.options(options)
+			}
+			cleanObject(&obj, options)
+			if !valuesEqual(objectToMap(&expected), objectToMap(&obj)) {
+				t.Errorf("Unexpected result.\nExpected: %v\nActual: %v", objectToMap(&expected), objectToMap(&obj))
+			}
+		})
+	}
+}